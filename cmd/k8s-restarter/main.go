@@ -2,19 +2,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shaardie/k8s-restarter/pkg/audit"
 	"github.com/shaardie/k8s-restarter/pkg/config"
 	"github.com/shaardie/k8s-restarter/pkg/controller"
+	"github.com/shaardie/k8s-restarter/pkg/notify"
 	"github.com/shaardie/k8s-restarter/pkg/server"
+	"github.com/shaardie/k8s-restarter/pkg/tracing"
 
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,8 +33,18 @@ import (
 var (
 	kubeconfig         string
 	configFile         string
+	configDir          string
+	configConfigMap    string
+	printConfig        bool
 	leaseLockName      string
 	leaseLockNamespace string
+	pauseConfigMap     string
+	otlpEndpoint       string
+	otlpInsecure       bool
+	auditLog           string
+	configPollInterval time.Duration
+	once               bool
+	rolloutTimeout     time.Duration
 	id                 string
 	debug              bool
 )
@@ -41,7 +56,20 @@ func init() {
 	flag.StringVar(&id, "id", uuid.New().String(), "the holder identity name")
 	flag.StringVar(&leaseLockNamespace, "lease-lock-namespace", "", "the lease lock resource namespace")
 	flag.StringVar(&configFile, "config", "", "path to the configuration file")
+	flag.StringVar(&configDir, "config-dir", "", "path to a directory with YAML files merged over the configuration file in lexical order")
+	flag.StringVar(&configConfigMap, "config-configmap", "", "the ConfigMap (namespace/name) holding the configuration in the key config.yaml, read and watched via the Kubernetes API")
+	flag.BoolVar(&printConfig, "print-config", false, "print the effective configuration with the source of every value and exit")
+	flag.StringVar(&pauseConfigMap, "pause-configmap", "", "the ConfigMap holding the pause state in the lease lock namespace, defaults to <lease-lock-name>-pause")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "the OTLP/HTTP endpoint (host:port) to export traces to, tracing is disabled if empty")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "disable TLS for the OTLP/HTTP endpoint")
+	flag.StringVar(&auditLog, "audit-log", "", "path to the append-only audit log, - for stdout, disabled if empty")
+	flag.DurationVar(&configPollInterval, "config-poll-interval", 10*time.Second, "interval to check the configuration file for changes")
+	flag.BoolVar(&once, "once", false, "run a single reconcilation without leader election and HTTP server, print a JSON report and exit non-zero on failures")
+	flag.DurationVar(&rolloutTimeout, "rollout-timeout", 0, "with -once, wait up to this duration for the rollouts of the restarted apps, no waiting if 0")
 	flag.Parse()
+	if pauseConfigMap == "" && leaseLockName != "" {
+		pauseConfigMap = leaseLockName + "-pause"
+	}
 }
 
 func getK8sClientset(kubeconfig string) (*kubernetes.Clientset, error) {
@@ -67,7 +95,99 @@ func getK8sClientset(kubeconfig string) (*kubernetes.Clientset, error) {
 	return clientset, nil
 }
 
+// runOnce runs a single reconcilation, prints the report and returns the
+// exit code
+func runOnce(ctx context.Context, logger *zap.Logger, clientset *kubernetes.Clientset, cfg *config.Config, notifier *notify.Notifier, auditLogger *audit.Log, shutdownTracing func(context.Context) error) int {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ctrl := controller.Controller{
+		Logger:         logger,
+		Cfg:            cfg,
+		Clientset:      clientset,
+		PauseNamespace: leaseLockNamespace,
+		PauseConfigMap: pauseConfigMap,
+		Notifier:       notifier,
+		Audit:          auditLogger,
+	}
+	report := ctrl.Once(ctx, rolloutTimeout)
+	code := 0
+	if !report.OK() {
+		code = 1
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logger.Sugar().Errorw("Failed to encode report", "error", err)
+		code = 1
+	} else {
+		fmt.Println(string(b))
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	err = notifier.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Sugar().Errorw("Failed to shut down notifier", "error", err)
+	}
+	err = shutdownTracing(shutdownCtx)
+	if err != nil {
+		logger.Sugar().Errorw("Failed to shut down tracing", "error", err)
+	}
+	err = auditLogger.Close()
+	if err != nil {
+		logger.Sugar().Errorw("Failed to close audit log", "error", err)
+	}
+	return code
+}
+
 func main() {
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "validate":
+			os.Exit(validateCommand(flag.Args()[1:]))
+		case "plan":
+			os.Exit(planCommand(flag.Args()[1:]))
+		case "simulate":
+			os.Exit(simulateCommand(flag.Args()[1:]))
+		case "restart":
+			os.Exit(restartCommand(flag.Args()[1:]))
+		default:
+			log.Fatalf("Unknown command %v\n", flag.Arg(0))
+		}
+	}
+
+	// Create Context with Cancel Option
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loader := &config.Loader{File: configFile, Dir: configDir}
+	var clientset *kubernetes.Clientset
+	var err error
+	if configConfigMap != "" {
+		clientset, err = getK8sClientset(kubeconfig)
+		if err != nil {
+			log.Fatalf("Failed to create kubernetes client set, %v\n", err)
+		}
+		loader.ConfigMap, err = config.NewConfigMap(clientset, configConfigMap)
+		if err != nil {
+			log.Fatalf("Invalid -config-configmap, %v\n", err)
+		}
+		err = loader.ConfigMap.Start(ctx)
+		if err != nil {
+			log.Fatalf("Failed to watch configuration, %v\n", err)
+		}
+	}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		log.Fatalf("Unable to read configuration, %v\n", err)
+	}
+	if printConfig {
+		err := snapshot.Print(os.Stdout)
+		if err != nil {
+			log.Fatalf("Failed to print configuration, %v\n", err)
+		}
+		os.Exit(0)
+	}
+
 	// Create logger
 	loggerCfg := zap.NewProductionConfig()
 	if debug {
@@ -78,19 +198,54 @@ func main() {
 		log.Fatalf("Failed to create logger, %v\n", err)
 	}
 
-	clientset, err := getK8sClientset(kubeconfig)
+	shutdownTracing, err := tracing.Setup(context.Background(), otlpEndpoint, otlpInsecure, id)
 	if err != nil {
-		logger.Sugar().Fatalw("Failed to create kubernetes client set", "error", err)
+		logger.Sugar().Fatalw("Failed to set up tracing", "error", err)
+	}
+
+	if clientset == nil {
+		clientset, err = getK8sClientset(kubeconfig)
+		if err != nil {
+			logger.Sugar().Fatalw("Failed to create kubernetes client set", "error", err)
+		}
+	}
+
+	// Report the status of the configuration next to the ConfigMap
+	status := &configStatus{configMap: loader.ConfigMap, logger: logger}
+	setConfigStatus := func(configErr error) {
+		status.set(ctx, configErr)
 	}
 
-	cfg, err := config.GetConfig(configFile)
+	cfg, issues := snapshot.Parse()
+	err = issues.Err()
+	setConfigStatus(err)
 	if err != nil {
-		logger.Sugar().Fatalw("Unable to read config file", "config file", configFile, "error", err)
+		logger.Sugar().Fatalw("Invalid config", "config file", configFile, "config dir", configDir, "configmap", configConfigMap, "error", err)
+	}
+	for _, w := range issues.Warnings() {
+		logger.Sugar().Warnw("Questionable configuration", "warning", w.String())
 	}
 	logger.Sugar().Debugw("Configuration read", "config", cfg)
 
+	notifier, err := notify.New(logger, cfg.Notifications)
+	if err != nil {
+		logger.Sugar().Fatalw("Failed to create notifier", "error", err)
+	}
+
+	auditLogger, err := audit.New(auditLog, id)
+	if err != nil {
+		logger.Sugar().Fatalw("Failed to create audit log", "error", err)
+	}
+
+	if once {
+		// A single run has no other replicas
+		status.lead(ctx)
+		os.Exit(runOnce(ctx, logger, clientset, cfg, notifier, auditLogger, shutdownTracing))
+	}
+
 	// Run Server
-	server := server.New(logger, ":8080")
+	server := server.New(logger, ":8080", clientset)
+	server.SetConfig(cfg)
 	go func() {
 		err := server.Run()
 		if err != nil && err != http.ErrServerClosed {
@@ -99,15 +254,41 @@ func main() {
 	}()
 
 	ctrl := controller.Controller{
-		Logger:    logger,
-		Cfg:       cfg,
-		Clientset: clientset,
-		Server:    server,
+		Logger:         logger,
+		Cfg:            cfg,
+		Clientset:      clientset,
+		Server:         server,
+		PauseNamespace: leaseLockNamespace,
+		PauseConfigMap: pauseConfigMap,
+		Notifier:       notifier,
+		Audit:          auditLogger,
 	}
 
-	// Create Context with Cancel Option
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Watch the configuration and reload on SIGHUP
+	watcher := config.NewWatcher(loader, configPollInterval, snapshot)
+	go watcher.Run(ctx, func(cfg *config.Config) error {
+		err := ctrl.SetConfig(cfg)
+		if err != nil {
+			return err
+		}
+		server.SetConfigError(nil)
+		setConfigStatus(nil)
+		logger.Sugar().Infow("Configuration reloaded", "config file", configFile, "config dir", configDir, "configmap", configConfigMap)
+		logger.Sugar().Debugw("Configuration read", "config", cfg)
+		return nil
+	}, func(err error) {
+		server.SetConfigError(err)
+		setConfigStatus(err)
+		logger.Sugar().Errorw("Rejected new configuration, keeping the last good one", "config file", configFile, "config dir", configDir, "configmap", configConfigMap, "error", err)
+	})
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			logger.Info("Received SIGHUP, reloading configuration")
+			watcher.Reload()
+		}
+	}()
 
 	// Stop controller and cancel context on shutdown
 	ch := make(chan os.Signal, 1)
@@ -116,6 +297,20 @@ func main() {
 		<-ch
 		logger.Info("Received termination, signaling shutdown")
 		ctrl.Stop()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		err := notifier.Shutdown(shutdownCtx)
+		if err != nil {
+			logger.Sugar().Errorw("Failed to shut down notifier", "error", err)
+		}
+		err = shutdownTracing(shutdownCtx)
+		if err != nil {
+			logger.Sugar().Errorw("Failed to shut down tracing", "error", err)
+		}
+		err = auditLogger.Close()
+		if err != nil {
+			logger.Sugar().Errorw("Failed to close audit log", "error", err)
+		}
 		cancel()
 	}()
 
@@ -138,6 +333,14 @@ func main() {
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
 				logger.Info("Start leading")
+				lease, err := clientset.CoordinationV1().Leases(leaseLockNamespace).Get(ctx, leaseLockName, metav1.GetOptions{})
+				if err != nil {
+					logger.Sugar().Errorw("Failed to get lease for the leader term", "error", err)
+				} else if lease.Spec.LeaseTransitions != nil {
+					auditLogger.SetLeaderTerm(*lease.Spec.LeaseTransitions)
+				}
+				status.lead(ctx)
+				server.SetActions(&ctrl)
 				ctrl.Run(ctx)
 			},
 			OnStoppedLeading: func() {
@@ -145,6 +348,7 @@ func main() {
 				os.Exit(0)
 			},
 			OnNewLeader: func(identity string) {
+				server.SetLeader(identity)
 				if identity == id {
 					return
 				}
@@ -153,3 +357,45 @@ func main() {
 		},
 	})
 }
+
+// configStatus reports the status of the configuration on the ConfigMap. Only
+// the leader writes it, so that the replicas do not race on the annotation
+// and the Events.
+type configStatus struct {
+	configMap *config.ConfigMap
+	logger    *zap.Logger
+
+	m       sync.Mutex
+	leading bool
+	err     error
+}
+
+// set records the status of the latest configuration and writes it, if
+// leading
+func (s *configStatus) set(ctx context.Context, configErr error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.err = configErr
+	if s.leading {
+		s.write(ctx)
+	}
+}
+
+// lead starts writing the status and writes the recorded one
+func (s *configStatus) lead(ctx context.Context) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.leading = true
+	s.write(ctx)
+}
+
+// write writes the recorded status. Must be called with the lock held.
+func (s *configStatus) write(ctx context.Context) {
+	if s.configMap == nil {
+		return
+	}
+	err := s.configMap.SetStatus(ctx, s.err)
+	if err != nil {
+		s.logger.Sugar().Errorw("Failed to set configuration status", "configmap", s.configMap.String(), "error", err)
+	}
+}
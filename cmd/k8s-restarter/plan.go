@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/controller"
+	"go.uber.org/zap"
+)
+
+// planCommand shows what the next reconcilation would do with every app
+// without changing anything. It returns the exit code, which is non-zero if
+// the plan failed or an app could not be evaluated.
+func planCommand(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	kubeconfig := fs.String("kubeconfig", "", "path to the kubeconfig file")
+	configFile := fs.String("config", "", "path to the configuration file")
+	configDir := fs.String("config-dir", "", "path to a directory with YAML files merged over the configuration file")
+	pauseConfigMap := fs.String("pause-configmap", "", "the ConfigMap (namespace/name) holding the pause state, not paused if empty")
+	output := fs.String("output", "table", "output format, table or json")
+	fs.Parse(args)
+
+	if *configFile == "" && *configDir == "" {
+		fmt.Fprintln(os.Stderr, "missing -config or -config-dir")
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %v\n", *output)
+		return 2
+	}
+	var pauseNamespace, pauseName string
+	if *pauseConfigMap != "" {
+		parts := strings.Split(*pauseConfigMap, "/")
+		if len(parts) != 2 {
+			fmt.Fprintf(os.Stderr, "invalid -pause-configmap %q, expected namespace/name\n", *pauseConfigMap)
+			return 2
+		}
+		pauseNamespace, pauseName = parts[0], parts[1]
+	}
+
+	loader := &config.Loader{File: *configFile, Dir: *configDir}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	cfg, issues := snapshot.Parse()
+	err = issues.Err()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config, %v\n", err)
+		return 1
+	}
+
+	clientset, err := getK8sClientset(*kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	ctrl := controller.Controller{
+		Logger:         zap.NewNop(),
+		Cfg:            cfg,
+		Clientset:      clientset,
+		PauseNamespace: pauseNamespace,
+		PauseConfigMap: pauseName,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	plan, err := ctrl.Plan(ctx, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to plan, %v\n", err)
+		return 1
+	}
+
+	if *output == "json" {
+		b, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode plan, %v\n", err)
+			return 1
+		}
+		fmt.Println(string(b))
+	} else {
+		printPlan(plan)
+	}
+
+	for _, p := range plan {
+		if p.Decision == controller.DecisionError {
+			return 1
+		}
+	}
+	return 0
+}
+
+// printPlan prints the plan as table followed by a summary
+func printPlan(plan []controller.PlannedApp) {
+	counts := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tKIND\tNAME\tDECISION\tREASON\tRULE\tDUE")
+	for _, p := range plan {
+		counts[p.Decision]++
+		reason := p.Status
+		if p.Error != "" {
+			reason += ": " + p.Error
+		}
+		rule, due := "-", "-"
+		if p.Matcher != "" {
+			rule = p.Matcher
+		}
+		if p.NextRestart != nil {
+			due = p.NextRestart.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", p.Namespace, p.Kind, p.Name, p.Decision, reason, rule, due)
+	}
+	w.Flush()
+	fmt.Printf("\nPlan: %v to restart, %v to skip, %v to ignore, %v errors.\n",
+		counts[controller.DecisionRestart], counts[controller.DecisionSkip], counts[controller.DecisionIgnore], counts[controller.DecisionError])
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/audit"
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/controller"
+	"github.com/shaardie/k8s-restarter/pkg/notify"
+	"go.uber.org/zap"
+)
+
+// restartCommand restarts a single app with the same checks and bookkeeping
+// as the controller. It returns the exit code, which is non-zero if the
+// restart was rejected or failed.
+func restartCommand(args []string) int {
+	fs := flag.NewFlagSet("restart", flag.ExitOnError)
+	kubeconfig := fs.String("kubeconfig", "", "path to the kubeconfig file")
+	namespace := fs.String("n", "default", "namespace of the app")
+	configFile := fs.String("config", "", "path to the configuration file")
+	configDir := fs.String("config-dir", "", "path to a directory with YAML files merged over the configuration file")
+	wait := fs.Bool("wait", false, "wait until the rollout is complete")
+	timeout := fs.Duration("timeout", 10*time.Minute, "timeout of the restart including the rollout")
+	force := fs.Bool("force", false, "skip the status, window and disruption budget checks, excluded apps and the pause are never overridden")
+	auditLog := fs.String("audit-log", "", "path to the append-only audit log, - for stdout, disabled if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: k8s-restarter restart <kind>/<name> -n namespace -config config.yaml [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	// Allow flags after the app
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	ref := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 || *configFile == "" && *configDir == "" {
+		fs.Usage()
+		return 2
+	}
+	kind, name, err := controller.ParseAppRef(ref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	loader := &config.Loader{File: *configFile, Dir: *configDir}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	cfg, issues := snapshot.Parse()
+	err = issues.Err()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config, %v\n", err)
+		return 1
+	}
+
+	loggerCfg := zap.NewDevelopmentConfig()
+	loggerCfg.Level.SetLevel(zap.WarnLevel)
+	logger, err := loggerCfg.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger, %v\n", err)
+		return 1
+	}
+	clientset, err := getK8sClientset(*kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	notifier, err := notify.New(logger, cfg.Notifications)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create notifier, %v\n", err)
+		return 1
+	}
+	hostname, _ := os.Hostname()
+	auditLogger, err := audit.New(*auditLog, hostname)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer auditLogger.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if u, err := user.Current(); err == nil {
+		ctx = audit.WithUser(ctx, u.Username)
+	}
+	ctrl := controller.Controller{
+		Logger:    logger,
+		Cfg:       cfg,
+		Clientset: clientset,
+		Notifier:  notifier,
+		Audit:     auditLogger,
+	}
+	code := func() int {
+		app, err := ctrl.RestartManually(ctx, *namespace, kind, name, *force)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Printf("%v %v/%v restarted\n", kind, *namespace, name)
+		if !*wait {
+			return 0
+		}
+		err = ctrl.WaitForRollout(ctx, app, 2*time.Second)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Printf("%v %v/%v rolled out\n", kind, *namespace, name)
+		return 0
+	}()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	err = notifier.Shutdown(shutdownCtx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to shut down notifier, %v\n", err)
+	}
+	return code
+}
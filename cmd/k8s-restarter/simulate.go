@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/controller"
+)
+
+// simulateCommand simulates the restarts of the apps in manifests with a
+// virtual clock and prints the timeline. It returns the exit code.
+func simulateCommand(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	configFile := fs.String("config", "", "path to the configuration file")
+	configDir := fs.String("config-dir", "", "path to a directory with YAML files merged over the configuration file")
+	start := fs.String("start", "", "start of the simulation in RFC3339, defaults to now")
+	horizon := fs.Duration("horizon", 14*24*time.Hour, "duration of the simulation")
+	output := fs.String("output", "table", "output format, table, csv or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: k8s-restarter simulate -config config.yaml [flags] manifests...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *configFile == "" && *configDir == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *output != "table" && *output != "csv" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %v\n", *output)
+		return 2
+	}
+	startTime := time.Now().Truncate(time.Second)
+	if *start != "" {
+		t, err := time.Parse(time.RFC3339, *start)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -start, %v\n", err)
+			return 2
+		}
+		startTime = t
+	}
+
+	loader := &config.Loader{File: *configFile, Dir: *configDir}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	cfg, issues := snapshot.Parse()
+	err = issues.Err()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config, %v\n", err)
+		return 1
+	}
+	manifests, err := controller.LoadManifests(fs.Args()...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	restarts, err := controller.Simulate(cfg, manifests, startTime, *horizon)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to simulate, %v\n", err)
+		return 1
+	}
+
+	switch *output {
+	case "json":
+		if restarts == nil {
+			restarts = []controller.SimulatedRestart{}
+		}
+		b, err := json.MarshalIndent(restarts, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode timeline, %v\n", err)
+			return 1
+		}
+		fmt.Println(string(b))
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"time", "namespace", "kind", "name", "rule", "due"})
+		for _, r := range restarts {
+			w.Write([]string{r.Time.Format(time.RFC3339), r.Namespace, r.Kind, r.Name, r.Rule, r.Due.Format(time.RFC3339)})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write timeline, %v\n", err)
+			return 1
+		}
+	default:
+		printTimeline(restarts, len(manifests.Apps), *horizon)
+	}
+	return 0
+}
+
+// printTimeline prints the restarts as table followed by a summary of the
+// load
+func printTimeline(restarts []controller.SimulatedRestart, apps int, horizon time.Duration) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tNAMESPACE\tKIND\tNAME\tRULE\tDUE")
+	peak, peakTime := 0, time.Time{}
+	n := 0
+	for i, r := range restarts {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", r.Time.Format(time.RFC3339), r.Namespace, r.Kind, r.Name, r.Rule, r.Due.Format(time.RFC3339))
+		n++
+		if i+1 == len(restarts) || !restarts[i+1].Time.Equal(r.Time) {
+			if n > peak {
+				peak, peakTime = n, r.Time
+			}
+			n = 0
+		}
+	}
+	w.Flush()
+	fmt.Printf("\n%v restarts of %v apps in %v", len(restarts), apps, horizon)
+	if peak > 0 {
+		fmt.Printf(", at most %v in one reconcilation at %v", peak, peakTime.Format(time.RFC3339))
+	}
+	fmt.Println(".")
+}
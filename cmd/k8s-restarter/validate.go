@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+)
+
+// validateCommand validates the layered configuration and prints the issues
+// found. It returns the exit code, which is non-zero if the configuration is
+// invalid.
+func validateCommand(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configFile := fs.String("config", "", "path to the configuration file")
+	configDir := fs.String("config-dir", "", "path to a directory with YAML files merged over the configuration file")
+	output := fs.String("output", "text", "output format, text or json")
+	fs.Parse(args)
+
+	if *configFile == "" && *configDir == "" {
+		fmt.Fprintln(os.Stderr, "missing -config or -config-dir")
+		return 2
+	}
+	loader := &config.Loader{File: *configFile, Dir: *configDir}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	_, issues := snapshot.Parse()
+
+	switch *output {
+	case "json":
+		if issues == nil {
+			issues = config.Issues{}
+		}
+		b, err := json.MarshalIndent(issues, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode issues, %v\n", err)
+			return 2
+		}
+		fmt.Println(string(b))
+	case "text":
+		for _, i := range issues {
+			severity := "error"
+			if i.Warning {
+				severity = "warning"
+			}
+			location := config.Source{Name: i.Source, Line: i.Line}.String()
+			if location == "" {
+				location = "config"
+			}
+			fmt.Printf("%v: %v: %v\n", location, severity, config.Issue{Path: i.Path, Message: i.Message})
+		}
+		if len(issues.Errors()) == 0 {
+			fmt.Println("valid")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format %v\n", *output)
+		return 2
+	}
+
+	if len(issues.Errors()) > 0 {
+		return 1
+	}
+	return 0
+}
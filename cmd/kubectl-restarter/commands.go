@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/controller"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// status is the state of the controller
+type status struct {
+	Leader        string     `json:"leader"`
+	LeaderRenewed *time.Time `json:"leaderRenewed,omitempty"`
+	Paused        bool       `json:"paused"`
+	ConfigStatus  string     `json:"configStatus"`
+}
+
+// statusCommand shows the leader, the pause state and the status of the
+// configuration
+func statusCommand(ctx context.Context, o *options, args []string) error {
+	clientset, err := o.client()
+	if err != nil {
+		return err
+	}
+	s := status{Leader: "-", ConfigStatus: "-"}
+	lease, err := clientset.CoordinationV1().Leases(o.controllerNamespace).Get(ctx, o.controllerName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get lease %v/%v, %w", o.controllerNamespace, o.controllerName, err)
+	}
+	if err == nil && lease.Spec.HolderIdentity != nil {
+		s.Leader = *lease.Spec.HolderIdentity
+		if lease.Spec.RenewTime != nil {
+			s.LeaderRenewed = &lease.Spec.RenewTime.Time
+		}
+	}
+	ctrl := controller.Controller{
+		Clientset:      clientset,
+		PauseNamespace: o.controllerNamespace,
+		PauseConfigMap: o.controllerName + "-pause",
+	}
+	s.Paused, err = ctrl.IsPaused(ctx)
+	if err != nil {
+		return err
+	}
+	cm, err := clientset.CoreV1().ConfigMaps(o.controllerNamespace).Get(ctx, o.controllerName, metav1.GetOptions{})
+	if err == nil && cm.Annotations[config.StatusAnnotation] != "" {
+		s.ConfigStatus = cm.Annotations[config.StatusAnnotation]
+	}
+
+	if o.output == "json" {
+		return printJSON(s)
+	}
+	leader := s.Leader
+	if s.LeaderRenewed != nil {
+		leader += fmt.Sprintf(" (renewed %v ago)", time.Since(*s.LeaderRenewed).Round(time.Second))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Leader:\t%v\n", leader)
+	fmt.Fprintf(w, "Paused:\t%v\n", s.Paused)
+	fmt.Fprintf(w, "Configuration:\t%v\n", s.ConfigStatus)
+	return w.Flush()
+}
+
+// nextCommand shows the next restarts of the apps in the namespace
+func nextCommand(ctx context.Context, o *options, args []string) error {
+	ctrl, err := o.controller(ctx)
+	if err != nil {
+		return err
+	}
+	plan, err := ctrl.Plan(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	// Soonest first, apps without restart last
+	sort.SliceStable(plan, func(i, j int) bool {
+		a, b := plan[i].NextRestart, plan[j].NextRestart
+		return a != nil && (b == nil || a.Before(*b))
+	})
+
+	if o.output == "json" {
+		return printJSON(plan)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tNEXT RESTART\tSTATUS\tRULE")
+	for _, p := range plan {
+		rule := p.Matcher
+		if rule == "" {
+			rule = "-"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", p.Kind, p.Name, formatTime(p.NextRestart), p.Status, rule)
+	}
+	return w.Flush()
+}
+
+// historyCommand shows the revisions of an app and what caused them
+func historyCommand(ctx context.Context, o *options, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one app like deploy/name")
+	}
+	kind, name, err := controller.ParseAppRef(args[0])
+	if err != nil {
+		return err
+	}
+	clientset, err := o.client()
+	if err != nil {
+		return err
+	}
+	ctrl := controller.Controller{Clientset: clientset}
+	history, err := ctrl.History(ctx, o.namespace, kind, name)
+	if err != nil {
+		return err
+	}
+
+	if o.output == "json" {
+		return printJSON(history)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tCREATED\tCAUSE\tRESTARTED AT")
+	for _, r := range history {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", r.Revision, r.Created.Format(time.RFC3339), r.Cause, formatTime(r.RestartedAt))
+	}
+	return w.Flush()
+}
+
+// explainCommand explains the decision of the controller on an app
+func explainCommand(ctx context.Context, o *options, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one app like deploy/name")
+	}
+	kind, name, err := controller.ParseAppRef(args[0])
+	if err != nil {
+		return err
+	}
+	ctrl, err := o.controller(ctx)
+	if err != nil {
+		return err
+	}
+	p, err := ctrl.Explain(ctx, o.namespace, kind, name, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if o.output == "json" {
+		return printJSON(p)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "App:\t%v %v/%v\n", p.Kind, p.Namespace, p.Name)
+	if p.Rule == nil {
+		fmt.Fprintf(w, "Rule:\tnone matches\n")
+	} else {
+		fmt.Fprintf(w, "Rule:\t%v\n", p.Rule.Name)
+		fmt.Fprintf(w, "Action:\t%v\n", p.Rule.Action)
+		if p.Rule.Selector != nil {
+			b, _ := json.Marshal(p.Rule.Selector)
+			fmt.Fprintf(w, "Selector:\t%s\n", b)
+		}
+		if p.Rule.Action == config.ActionRestart && p.Rule.Schedule != nil {
+			tz := p.Rule.Schedule.TimeZone
+			if tz == "" {
+				tz = "UTC"
+			}
+			fmt.Fprintf(w, "Schedule:\t%v %v\n", p.Rule.Schedule.Cron, tz)
+		} else if p.Rule.Action == config.ActionRestart {
+			fmt.Fprintf(w, "Interval:\t%v\n", p.Rule.RestartInterval)
+		}
+		if p.Rule.Window != nil {
+			tz := p.Rule.Window.TimeZone
+			if tz == "" {
+				tz = "UTC"
+			}
+			fmt.Fprintf(w, "Window:\t%v-%v %v\n", p.Rule.Window.Start, p.Rule.Window.End, tz)
+		}
+		if p.Rule.Strategy != nil {
+			fmt.Fprintf(w, "Strategy:\tmaxConcurrent %v, maxRestarts %v per %v\n",
+				p.Rule.Strategy.MaxConcurrent, p.Rule.Strategy.MaxRestarts, p.Rule.Strategy.BudgetPeriod)
+		}
+	}
+	fmt.Fprintf(w, "Last restart:\t%v\n", formatTime(p.LastRestart))
+	fmt.Fprintf(w, "Next restart:\t%v\n", formatTime(p.NextRestart))
+	status := p.Status
+	if p.Error != "" {
+		status += ": " + p.Error
+	}
+	fmt.Fprintf(w, "Status:\t%v\n", status)
+	fmt.Fprintf(w, "Decision:\t%v\n", p.Decision)
+	return w.Flush()
+}
@@ -0,0 +1,186 @@
+// kubectl-restarter is a kubectl plugin showing the restart schedule and
+// history of apps managed by k8s-restarter
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/controller"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const usage = `Show the restart schedule and history of apps managed by k8s-restarter.
+
+Usage:
+  kubectl restarter status                Show the state of the controller
+  kubectl restarter next [-n namespace]   Show the next restarts in a namespace
+  kubectl restarter history <kind>/<name> Show the revisions of an app
+  kubectl restarter explain <kind>/<name> Explain the decision on an app
+
+Run kubectl restarter <command> -h for the flags of a command.
+`
+
+// options are the flags common to all commands
+type options struct {
+	kubeconfig          string
+	context             string
+	namespace           string
+	controllerNamespace string
+	controllerName      string
+	configFile          string
+	output              string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	fs.StringVar(&o.context, "context", "", "the kubeconfig context to use")
+	fs.StringVar(&o.namespace, "n", "", "namespace of the apps, defaults to the namespace of the context")
+	fs.StringVar(&o.controllerNamespace, "controller-namespace", "k8s-restarter", "namespace of k8s-restarter")
+	fs.StringVar(&o.controllerName, "controller-name", "k8s-restarter", "name of the release of k8s-restarter, i.e. of its lease and ConfigMap")
+	fs.StringVar(&o.configFile, "config", "", "path to a configuration file to evaluate instead of the ConfigMap of k8s-restarter")
+	fs.StringVar(&o.output, "o", "table", "output format, table or json")
+}
+
+// parse parses the flags, which may follow the positional arguments like in
+// kubectl, and returns the positional arguments
+func (o *options) parse(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// client returns the clientset and sets the namespace from the kubeconfig,
+// if not set
+func (o *options) client() (*kubernetes.Clientset, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: o.context})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig, %w", err)
+	}
+	if o.namespace == "" {
+		o.namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get namespace from kubeconfig, %w", err)
+		}
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset from kubeconfig, %w", err)
+	}
+	return clientset, nil
+}
+
+// config returns the configuration from the file or the ConfigMap of
+// k8s-restarter. Environment overrides of the controller are not known.
+func (o *options) config(ctx context.Context, clientset *kubernetes.Clientset) (*config.Config, error) {
+	var content []byte
+	if o.configFile != "" {
+		var err error
+		content, err = ioutil.ReadFile(o.configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %v, %w", o.configFile, err)
+		}
+	} else {
+		cm, err := clientset.CoreV1().ConfigMaps(o.controllerNamespace).Get(ctx, o.controllerName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configuration, use -config to pass it, %w", err)
+		}
+		content = []byte(cm.Data[config.ConfigMapKey])
+	}
+	cfg, issues := config.Validate(content)
+	err := issues.Err()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config, %w", err)
+	}
+	return cfg, nil
+}
+
+// controller returns a controller evaluating the apps read-only
+func (o *options) controller(ctx context.Context) (*controller.Controller, error) {
+	clientset, err := o.client()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := o.config(ctx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	return &controller.Controller{
+		Logger:    zap.NewNop(),
+		Cfg:       cfg,
+		Clientset: clientset,
+		Namespace: o.namespace,
+	}, nil
+}
+
+// printJSON prints v as indented JSON
+func printJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output, %w", err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// formatTime formats an optional time
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	commands := map[string]func(context.Context, *options, []string) error{
+		"status":  statusCommand,
+		"next":    nextCommand,
+		"history": historyCommand,
+		"explain": explainCommand,
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	o := &options{}
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	o.register(fs)
+	args := o.parse(fs, os.Args[2:])
+	if o.output != "table" && o.output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %v\n", o.output)
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := command(ctx, o, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Decisions recorded in the audit log
+const (
+	DecisionRestart  = "restart"
+	DecisionPostpone = "postpone"
+	DecisionPause    = "pause"
+	DecisionResume   = "resume"
+)
+
+// Record is a single entry of the audit log
+type Record struct {
+	Time       time.Time       `json:"time"`
+	Identity   string          `json:"identity"`
+	LeaderTerm int32           `json:"leaderTerm"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Kind       string          `json:"kind,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Decision   string          `json:"decision"`
+	Trigger    string          `json:"trigger"`
+	User       string          `json:"user,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	Patch      json.RawMessage `json:"patch,omitempty"`
+	// Object is the object sent for actions creating or updating a whole
+	// object instead of patching it
+	Object       json.RawMessage `json:"object,omitempty"`
+	ResponseCode int             `json:"responseCode"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// Log is an append-only audit log of all mutating actions written as JSON
+// Lines. A nil Log discards all records.
+type Log struct {
+	w          io.WriteCloser
+	identity   string
+	leaderTerm int32
+	m          *sync.Mutex
+}
+
+// New returns a new audit log appending to the file at path or writing to
+// stdout, if path is "-". If path is empty, nil is returned.
+func New(path, identity string) (*Log, error) {
+	if path == "" {
+		return nil, nil
+	}
+	l := &Log{
+		identity: identity,
+		m:        &sync.Mutex{},
+	}
+	if path == "-" {
+		l.w = os.Stdout
+		return l, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %v, %w", path, err)
+	}
+	l.w = f
+	return l, nil
+}
+
+// SetLeaderTerm sets the term of the leadership recorded in the audit log
+func (l *Log) SetLeaderTerm(term int32) {
+	if l == nil {
+		return
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.leaderTerm = term
+}
+
+// Record writes a record to the audit log. The time, the identity and the
+// leader term are set automatically and the response code is derived from
+// the error of the request to the Kubernetes API.
+func (l *Log) Record(r Record, err error) error {
+	if l == nil {
+		return nil
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	r.Time = time.Now()
+	r.Identity = l.identity
+	r.LeaderTerm = l.leaderTerm
+	r.ResponseCode = ResponseCode(err)
+	if err != nil {
+		r.Error = err.Error()
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record, %w", err)
+	}
+	_, err = l.w.Write(append(b, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write audit record, %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit log
+func (l *Log) Close() error {
+	if l == nil || l.w == os.Stdout {
+		return nil
+	}
+	return l.w.Close()
+}
+
+// ResponseCode returns the HTTP response code of a request to the Kubernetes
+// API from its error
+func ResponseCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return int(status.Status().Code)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return 0
+}
+
+// userKey is the context key of the user
+type userKey struct{}
+
+// WithUser returns a context with the user triggering an action
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the user triggering an action from the context
+func UserFrom(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
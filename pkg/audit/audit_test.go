@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestLog_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := New(path, "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetLeaderTerm(3)
+	for _, err := range []error{nil, errors.New("failed")} {
+		err = l.Record(Record{
+			Namespace: "namespace",
+			Kind:      "Deployment",
+			Name:      "name",
+			Decision:  DecisionRestart,
+			Trigger:   "reconcile",
+			Patch:     []byte(`{"metadata":{}}`),
+		}, err)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %v records, want 2", len(lines))
+	}
+	r := Record{}
+	err = json.Unmarshal([]byte(lines[0]), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Identity != "identity" || r.LeaderTerm != 3 || r.ResponseCode != http.StatusOK || string(r.Patch) != `{"metadata":{}}` {
+		t.Errorf("unexpected record %+v", r)
+	}
+	err = json.Unmarshal([]byte(lines[1]), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Error != "failed" {
+		t.Errorf("error = %q, want %q", r.Error, "failed")
+	}
+}
+
+func TestResponseCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "Success", err: nil, want: http.StatusOK},
+		{name: "Conflict", err: apierrors.NewConflict(schema.GroupResource{Resource: "deployments"}, "name", errors.New("conflict")), want: http.StatusConflict},
+		{name: "Timeout", err: context.DeadlineExceeded, want: http.StatusGatewayTimeout},
+		{name: "Unknown", err: errors.New("unknown"), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResponseCode(tt.err); got != tt.want {
+				t.Errorf("ResponseCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
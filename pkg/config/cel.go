@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// celCostLimit limits the cost of a single evaluation of an expression
+const celCostLimit = 100000
+
+var (
+	opsExpressionErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "k8s_restarter_selector_expression_errors_total",
+		Help: "The total number of failed evaluations of selector expressions",
+	})
+
+	celEnvOnce sync.Once
+	celEnv     *cel.Env
+	celEnvErr  error
+)
+
+// compileExpression compiles the CEL expression. If programs is not nil, the
+// compiled programs are cached in it by expression.
+func compileExpression(expr string, programs map[string]cel.Program) (cel.Program, error) {
+	if prg, ok := programs[expr]; ok {
+		return prg, nil
+	}
+
+	celEnvOnce.Do(func() {
+		celEnv, celEnvErr = cel.NewEnv(cel.Variable("object", cel.DynType))
+	})
+	if celEnvErr != nil {
+		return nil, fmt.Errorf("failed to create CEL environment, %w", celEnvErr)
+	}
+	ast, issues := celEnv.Compile(expr)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q, %w", expr, issues.Err())
+	}
+	if t := ast.OutputType(); t != cel.BoolType && t != cel.DynType {
+		return nil, fmt.Errorf("expression %q returns %v instead of bool", expr, t)
+	}
+	prg, err := celEnv.Program(ast, cel.CostLimit(celCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create program of expression %q, %w", expr, err)
+	}
+	if programs != nil {
+		programs[expr] = prg
+	}
+	return prg, nil
+}
+
+// evalExpression evaluates the program against the object. The object is
+// available as variable object in its unstructured form.
+func evalExpression(prg cel.Program, t Target) (bool, error) {
+	if t.Object == nil {
+		return false, fmt.Errorf("no object")
+	}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(t.Object)
+	if err != nil {
+		return false, fmt.Errorf("failed to convert object, %w", err)
+	}
+	if _, ok := object["kind"]; !ok {
+		object["kind"] = t.Kind
+	}
+	out, _, err := prg.Eval(map[string]interface{}{"object": object})
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %v instead of bool", out.Type())
+	}
+	return b, nil
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelector_Matches_expression(t *testing.T) {
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "shop",
+			Namespace:   "web",
+			Annotations: map[string]string{"critical": "true"},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	target := Target{Namespace: "web", Kind: "Deployment", Name: "shop", Object: deployment}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+		wantErr    bool
+	}{
+		{"Replicas", "object.spec.replicas > 1", true, false},
+		{"Annotation", `object.spec.replicas > 1 && !("critical" in object.metadata.annotations)`, false, false},
+		{"Kind", `object.kind == "Deployment"`, true, false},
+		{"Missing map", `has(object.metadata.labels) && "app" in object.metadata.labels`, false, false},
+		{"Missing field", "object.spec.paused", false, true},
+		{"Not bool", "object.spec.replicas", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Selector{Expression: tt.expression}
+			if got := s.Matches(target); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+			_, err := s.match(target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("match() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_compileExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{"Valid", "object.spec.replicas > 1", false},
+		{"Syntax error", "object.spec.replicas >", true},
+		{"Not bool", "1 + 1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileExpression(tt.expression, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConfig_expressionPrograms(t *testing.T) {
+	content := []byte(`reconcilationInterval: 1m
+restartInterval: 24h
+rules:
+  - selector:
+      expression: object.spec.replicas > 1
+    action: ignore
+  - selector:
+      kinds: [Deployment]
+      expression: object.spec.replicas > 1
+`)
+	first, err := ParseConfig(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ParseConfig(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The programs are shared within a configuration, but not across
+	// configurations, so that they are released on a reload
+	if first.Rules[0].Selector.compiled.expression != first.Rules[1].Selector.compiled.expression {
+		t.Errorf("equal expressions of a configuration are compiled twice")
+	}
+	if first.Rules[0].Selector.compiled.expression == second.Rules[0].Selector.compiled.expression {
+		t.Errorf("programs are shared across configurations")
+	}
+}
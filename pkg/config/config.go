@@ -3,19 +3,36 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"time"
 
-	"k8s.io/apimachinery/pkg/util/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// defaultLivenessIntervals is the default number of reconcilation intervals
+// without successful reconcilation after which the controller is unhealthy
+const defaultLivenessIntervals = 5
+
 // Config represents the configuration of this service
 type Config struct {
 	ReconcilationInterval       time.Duration `json:"-"`
 	ReconcilationIntervalHelper string        `json:"reconcilationInterval"`
 	RestartInterval             time.Duration `json:"-"`
 	RestartIntervalHelper       string        `json:"restartInterval"`
-	Include                     Matcher       `json:"include"`
-	Exclude                     Matcher       `json:"exclude"`
+	// LivenessIntervals is the number of reconcilation intervals without
+	// successful reconcilation after which the controller is unhealthy.
+	// Negative values disable the check.
+	LivenessIntervals int     `json:"livenessIntervals"`
+	Include           Matcher `json:"include"`
+	Exclude           Matcher `json:"exclude"`
+	// SkipControllerOwned skips apps with a controller owner like an
+	// operator, unless they are selected by a selector on owners. Defaults to
+	// true.
+	SkipControllerOwned *bool `json:"skipControllerOwned"`
+	// Rules are ordered and the first matching rule decides. They replace
+	// Include and Exclude.
+	Rules         []Rule        `json:"rules"`
+	Notifications Notifications `json:"notifications"`
 }
 
 type Matcher struct {
@@ -23,37 +40,137 @@ type Matcher struct {
 	Selectors []Selector `json:"selectors"`
 }
 
+// Selector selects apps. All set fields have to match.
 type Selector struct {
-	Namespace   string            `json:"namespace"`
-	MatchLabels map[string]string `json:"matchLabels"`
+	// Namespace is a glob pattern like team-* matching the namespace
+	Namespace string `json:"namespace"`
+	// NamespaceRegex is a regular expression matching the whole namespace
+	NamespaceRegex string `json:"namespaceRegex"`
+	// Kinds of the apps, e.g. Deployment
+	Kinds []string `json:"kinds"`
+	// Name is a glob pattern matching the name of the app
+	Name string `json:"name"`
+	// NameRegex is a regular expression matching the whole name of the app
+	NameRegex        string                            `json:"nameRegex"`
+	MatchLabels      map[string]string                 `json:"matchLabels"`
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions"`
+	// NamespaceSelector selects by the labels of the Namespace object
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector"`
+	// Images selects by the images of the containers. One of the images has
+	// to match.
+	Images []ImageSelector `json:"images"`
+	// Owners selects by the owner references. One of the owner references
+	// has to match.
+	Owners []OwnerSelector `json:"owners"`
+	// HasControllerOwner selects apps with or without a controller owner
+	HasControllerOwner *bool `json:"hasControllerOwner"`
+	// Expression is a CEL expression evaluated against the app as variable
+	// object
+	Expression string `json:"expression"`
+
+	compiled *compiledSelector
+}
+
+// Notifications configures the notifications about restarts
+type Notifications struct {
+	Webhooks    []Webhook     `json:"webhooks"`
+	CloudEvents []CloudEvents `json:"cloudEvents"`
+}
+
+// Webhook configures a HTTP webhook target
+type Webhook struct {
+	// URL of the webhook
+	URL string `json:"url"`
+	// SecretFile is the path to a file containing the secret used to sign
+	// the payload using HMAC-SHA256. No signing, if empty.
+	SecretFile string `json:"secretFile"`
+	// Template is a Go template for the payload. The event is sent as JSON,
+	// if empty.
+	Template string `json:"template"`
+	// ContentType of the payload, defaults to application/json
+	ContentType string `json:"contentType"`
+	// Events to sent to the webhook. All events are sent, if empty.
+	Events []string `json:"events"`
+	// MaxRetries is the maximum number of retries of a failed delivery,
+	// defaults to 5. 0 disables the retries.
+	MaxRetries *int `json:"maxRetries"`
+}
+
+// CloudEvents configures a sink receiving CloudEvents in the structured JSON
+// mode via HTTP
+type CloudEvents struct {
+	// Sink is the URL of the sink
+	Sink string `json:"sink"`
+	// Source is the source attribute of the events, defaults to
+	// /k8s-restarter
+	Source string `json:"source"`
+	// Events to sent to the sink. All events are sent, if empty.
+	Events []string `json:"events"`
+	// MaxRetries is the maximum number of retries of a failed delivery,
+	// defaults to 5. 0 disables the retries.
+	MaxRetries *int `json:"maxRetries"`
+}
+
+// redacted replaces secrets in the redacted configuration
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the configuration safe to show to anyone. The
+// paths, queries and credentials of the notification URLs, which often
+// contain tokens, and the paths of secret files are replaced.
+func (c *Config) Redacted() *Config {
+	if c == nil {
+		return nil
+	}
+	r := *c
+	r.Notifications.Webhooks = make([]Webhook, len(c.Notifications.Webhooks))
+	for i, w := range c.Notifications.Webhooks {
+		w.URL = redactURL(w.URL)
+		if w.SecretFile != "" {
+			w.SecretFile = redacted
+		}
+		r.Notifications.Webhooks[i] = w
+	}
+	r.Notifications.CloudEvents = make([]CloudEvents, len(c.Notifications.CloudEvents))
+	for i, ce := range c.Notifications.CloudEvents {
+		ce.Sink = redactURL(ce.Sink)
+		r.Notifications.CloudEvents[i] = ce
+	}
+	return &r
+}
+
+// redactURL keeps only the scheme and host of an URL
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	redactedURL := u.Scheme + "://" + u.Host
+	if u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		redactedURL += "/" + redacted
+	}
+	return redactedURL
 }
 
 // GetConfig reads and parses the configuration from the configuration file
 func GetConfig(cf string) (*Config, error) {
-	cfg := &Config{}
 	content, err := ioutil.ReadFile(cf)
 	if err != nil {
-		return cfg, fmt.Errorf("failed to read config file %v, %w", cf, err)
+		return &Config{}, fmt.Errorf("failed to read config file %v, %w", cf, err)
 	}
-	err = yaml.Unmarshal(content, cfg)
+	cfg, err := ParseConfig(content)
 	if err != nil {
-		return cfg, fmt.Errorf("failed to unmarshal config file %v, %w", cf, err)
+		return cfg, fmt.Errorf("failed to parse config file %v, %w", cf, err)
 	}
-	if cfg.RestartIntervalHelper != "" {
-		d, err := time.ParseDuration(cfg.RestartIntervalHelper)
-		if err != nil {
-			return cfg, fmt.Errorf("failed to parse duration %v in config file %v, %w", cfg.RestartIntervalHelper, cf, err)
-
-		}
-		cfg.RestartInterval = d
-	}
-	if cfg.ReconcilationIntervalHelper != "" {
-		d, err := time.ParseDuration(cfg.ReconcilationIntervalHelper)
-		if err != nil {
-			return cfg, fmt.Errorf("failed to parse duration %v in config file %v, %w", cfg.ReconcilationIntervalHelper, cf, err)
+	return cfg, nil
+}
 
-		}
-		cfg.ReconcilationInterval = d
+// ParseConfig parses and validates the configuration. Warnings are ignored,
+// use Validate to get them.
+func ParseConfig(content []byte) (*Config, error) {
+	cfg, issues := Validate(content)
+	err := issues.Err()
+	if err != nil {
+		return cfg, fmt.Errorf("invalid config, %w", err)
 	}
 	return cfg, nil
 }
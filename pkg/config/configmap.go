@@ -0,0 +1,184 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ConfigMapKey is the key of the configuration in the data of a ConfigMap
+	ConfigMapKey = "config.yaml"
+	// StatusAnnotation is set on the ConfigMap to the status of the last
+	// configuration read from it
+	StatusAnnotation = "k8s-restarter.kubernetes.io/configStatus"
+
+	statusValid = "valid"
+)
+
+// configMapSyncTimeout is the maximum time to wait for the initial sync of
+// the ConfigMap
+var configMapSyncTimeout = 30 * time.Second
+
+// ConfigMap is a ConfigMap holding the configuration, which is read and
+// watched via the Kubernetes API instead of a mounted volume, so that changes
+// take effect in seconds
+type ConfigMap struct {
+	Clientset kubernetes.Interface
+	Namespace string
+	Name      string
+
+	m       sync.Mutex
+	lister  corelisters.ConfigMapLister
+	changed chan struct{}
+}
+
+// NewConfigMap returns the ConfigMap referenced as namespace/name
+func NewConfigMap(clientset kubernetes.Interface, ref string) (*ConfigMap, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid configmap %q, expected namespace/name", ref)
+	}
+	return &ConfigMap{
+		Clientset: clientset,
+		Namespace: parts[0],
+		Name:      parts[1],
+		changed:   make(chan struct{}, 1),
+	}, nil
+}
+
+func (c *ConfigMap) String() string {
+	return fmt.Sprintf("configmap %v/%v", c.Namespace, c.Name)
+}
+
+// Start starts an informer watching the ConfigMap until the context is done
+// and waits for the initial sync. It returns an error, if the ConfigMap is not
+// synced within configMapSyncTimeout, e.g. since reading it is forbidden.
+func (c *ConfigMap) Start(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.Clientset, 0,
+		informers.WithNamespace(c.Namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = fields.OneTermEqualSelector("metadata.name", c.Name).String()
+		}))
+	informer := factory.Core().V1().ConfigMaps()
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { c.notify() },
+		UpdateFunc: func(interface{}, interface{}) { c.notify() },
+		DeleteFunc: func(interface{}) { c.notify() },
+	})
+	synced := informer.Informer().HasSynced
+	lister := informer.Lister()
+	factory.Start(ctx.Done())
+	syncCtx, cancel := context.WithTimeout(ctx, configMapSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), synced) {
+		return fmt.Errorf("failed to sync %v within %v", c, configMapSyncTimeout)
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.lister = lister
+	return nil
+}
+
+// notify signals a change without blocking
+func (c *ConfigMap) notify() {
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// get returns the ConfigMap from the cache of the informer
+func (c *ConfigMap) get() (*v1.ConfigMap, error) {
+	c.m.Lock()
+	lister := c.lister
+	c.m.Unlock()
+	if lister == nil {
+		return nil, fmt.Errorf("%v is not watched", c)
+	}
+	cm, err := lister.ConfigMaps(c.Namespace).Get(c.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %v, %w", c, err)
+	}
+	return cm, nil
+}
+
+// read returns the configuration in the ConfigMap
+func (c *ConfigMap) read() ([]byte, error) {
+	cm, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	content, ok := cm.Data[ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("missing key %v in %v", ConfigMapKey, c)
+	}
+	return []byte(content), nil
+}
+
+// SetStatus reports the status of the configuration read from the ConfigMap
+// in the StatusAnnotation and, if the status changed, as Event on the
+// ConfigMap. A nil error means the configuration is in use.
+func (c *ConfigMap) SetStatus(ctx context.Context, configErr error) error {
+	cm, err := c.get()
+	if err != nil {
+		return err
+	}
+	status := statusValid
+	eventType, reason := v1.EventTypeNormal, "ConfigApplied"
+	if configErr != nil {
+		status = "rejected: " + configErr.Error()
+		eventType, reason = v1.EventTypeWarning, "ConfigRejected"
+	}
+	if cm.Annotations[StatusAnnotation] == status {
+		return nil
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, StatusAnnotation, status)
+	_, err = c.Clientset.CoreV1().ConfigMaps(c.Namespace).Patch(ctx, c.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to set status of %v, %w", c, err)
+	}
+
+	message := "Configuration applied"
+	if configErr != nil {
+		message = "Configuration rejected, keeping the last good one: " + configErr.Error()
+	}
+	now := metav1.NewTime(time.Now())
+	_, err = c.Clientset.CoreV1().Events(c.Namespace).Create(ctx, &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: c.Name + ".",
+			Namespace:    c.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion:      "v1",
+			Kind:            "ConfigMap",
+			Namespace:       c.Namespace,
+			Name:            c.Name,
+			UID:             cm.UID,
+			ResourceVersion: cm.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         v1.EventSource{Component: "k8s-restarter"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create event for %v, %w", c, err)
+	}
+	return nil
+}
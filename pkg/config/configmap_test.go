@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestConfigMap(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "restarter"},
+		Data:       map[string]string{ConfigMapKey: "reconcilationInterval: 1m\nrestartInterval: 10m"},
+	})
+	cm, err := NewConfigMap(clientset, "ops/restarter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = cm.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &Loader{ConfigMap: cm, Environ: []string{}}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, issues := snapshot.Parse()
+	if issues.Err() != nil || cfg.RestartInterval != 10*time.Minute {
+		t.Fatalf("Parse() = %+v, %v", cfg, issues)
+	}
+
+	// Drain the notification of the initial sync
+	select {
+	case <-loader.changed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("missing notification of initial sync")
+	}
+	_, err = clientset.CoreV1().ConfigMaps("ops").Update(ctx, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "restarter"},
+		Data:       map[string]string{ConfigMapKey: "reconcilationInterval: 1m\nrestartInterval: 20m"},
+	}, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-loader.changed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("missing notification of update")
+	}
+
+	err = cm.SetStatus(ctx, errors.New("invalid config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := clientset.CoreV1().ConfigMaps("ops").Get(ctx, "restarter", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Annotations[StatusAnnotation] != "rejected: invalid config" {
+		t.Errorf("annotation = %q", got.Annotations[StatusAnnotation])
+	}
+	events, err := clientset.CoreV1().Events("ops").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Reason != "ConfigRejected" || events.Items[0].Type != v1.EventTypeWarning {
+		t.Errorf("events = %+v", events.Items)
+	}
+
+	_, err = NewConfigMap(clientset, "restarter")
+	if err == nil {
+		t.Error("NewConfigMap() without namespace succeeded")
+	}
+}
+
+func TestConfigMap_StartTimeout(t *testing.T) {
+	timeout := configMapSyncTimeout
+	configMapSyncTimeout = 100 * time.Millisecond
+	defer func() {
+		configMapSyncTimeout = timeout
+	}()
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "", nil)
+	})
+	cm, err := NewConfigMap(clientset, "ops/restarter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = cm.Start(ctx)
+	if err == nil {
+		t.Fatal("Start() succeeded without access to the ConfigMap")
+	}
+}
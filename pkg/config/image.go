@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	defaultRegistry = "docker.io"
+	defaultTag      = "latest"
+)
+
+// ImageSelector selects apps by the images of their containers and init
+// containers. The fields are glob patterns, where * does not match /. Empty
+// fields match everything.
+type ImageSelector struct {
+	// Registry like docker.io or ghcr.io
+	Registry string `json:"registry"`
+	// Repository like library/nginx
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	// Digest like sha256:..., matched exactly
+	Digest string `json:"digest"`
+}
+
+// Image is a container image reference
+type Image struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImage parses and normalizes an image reference like the container
+// runtimes do, e.g. nginx becomes docker.io/library/nginx:latest
+func ParseImage(ref string) Image {
+	img := splitImage(ref)
+	if img.Tag == "" && img.Digest == "" {
+		img.Tag = defaultTag
+	}
+	return img
+}
+
+// ParseImageSelector parses a pattern like docker.io/library/alpine:3.* into
+// an image selector. Other than for ParseImage, a missing tag matches all
+// tags.
+func ParseImageSelector(pattern string) (ImageSelector, error) {
+	if pattern == "" {
+		return ImageSelector{}, fmt.Errorf("empty image pattern")
+	}
+	img := splitImage(pattern)
+	s := ImageSelector{
+		Registry:   img.Registry,
+		Repository: img.Repository,
+		Tag:        img.Tag,
+		Digest:     img.Digest,
+	}
+	return s, s.validate()
+}
+
+// splitImage splits an image reference into its parts
+func splitImage(ref string) Image {
+	img := Image{}
+	if i := strings.Index(ref, "@"); i >= 0 {
+		img.Digest = ref[i+1:]
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		img.Tag = ref[i+1:]
+		ref = ref[:i]
+	}
+	img.Registry = defaultRegistry
+	if i := strings.Index(ref, "/"); i >= 0 && (strings.ContainsAny(ref[:i], ".:") || ref[:i] == "localhost") {
+		img.Registry = ref[:i]
+		ref = ref[i+1:]
+	}
+	if img.Registry == defaultRegistry && !strings.Contains(ref, "/") {
+		ref = "library/" + ref
+	}
+	img.Repository = ref
+	return img
+}
+
+// validate checks the glob patterns
+func (s ImageSelector) validate() error {
+	for _, p := range []string{s.Registry, s.Repository, s.Tag} {
+		_, err := path.Match(p, "")
+		if err != nil {
+			return fmt.Errorf("invalid image pattern %q, %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Matches checks if the image matches the selector
+func (s ImageSelector) Matches(img Image) bool {
+	for _, m := range [][2]string{
+		{s.Registry, img.Registry},
+		{s.Repository, img.Repository},
+		{s.Tag, img.Tag},
+	} {
+		if m[0] == "" {
+			continue
+		}
+		if ok, _ := path.Match(m[0], m[1]); !ok {
+			return false
+		}
+	}
+	return s.Digest == "" || s.Digest == img.Digest
+}
+
+// MatchesAny returns the first of the image references matching the
+// selector. Returns false, if none matches.
+func (s ImageSelector) MatchesAny(refs []string) (string, bool) {
+	for _, ref := range refs {
+		if s.Matches(ParseImage(ref)) {
+			return ref, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,50 @@
+package config
+
+import "testing"
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want Image
+	}{
+		{"nginx", Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{"alpine:3.15", Image{Registry: "docker.io", Repository: "library/alpine", Tag: "3.15"}},
+		{"shop/web:1.2", Image{Registry: "docker.io", Repository: "shop/web", Tag: "1.2"}},
+		{"ghcr.io/shop/web", Image{Registry: "ghcr.io", Repository: "shop/web", Tag: "latest"}},
+		{"localhost:5000/web:dev", Image{Registry: "localhost:5000", Repository: "web", Tag: "dev"}},
+		{"alpine@sha256:abc", Image{Registry: "docker.io", Repository: "library/alpine", Digest: "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := ParseImage(tt.ref); got != tt.want {
+				t.Errorf("ParseImage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageSelector_MatchesAny(t *testing.T) {
+	tests := []struct {
+		pattern string
+		refs    []string
+		want    bool
+	}{
+		{"alpine", []string{"nginx", "alpine:3.15"}, true},
+		{"alpine:3.*", []string{"docker.io/library/alpine:3.15"}, true},
+		{"alpine:3.*", []string{"alpine:edge"}, false},
+		{"ghcr.io/shop/*", []string{"ghcr.io/shop/web:1.2"}, true},
+		{"ghcr.io/shop/*", []string{"shop/web:1.2"}, false},
+		{"alpine@sha256:abc", []string{"alpine@sha256:abc"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			s, err := ParseImageSelector(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, got := s.MatchesAny(tt.refs); got != tt.want {
+				t.Errorf("MatchesAny() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the
+// configuration, e.g. K8S_RESTARTER_RESTARTINTERVAL
+const EnvPrefix = "K8S_RESTARTER_"
+
+// defaults is the first layer of every configuration
+var defaults = []byte(fmt.Sprintf("livenessIntervals: %v\nskipControllerOwned: true\n", defaultLivenessIntervals))
+
+// variableRegexp matches ${VAR} and the escaped form $${VAR}
+var variableRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Source is where a value of the configuration came from
+type Source struct {
+	// Name of the file or environment variable
+	Name string
+	// Line in the file, 0 if unknown
+	Line int
+}
+
+func (s Source) String() string {
+	if s.Line > 0 {
+		return fmt.Sprintf("%v:%v", s.Name, s.Line)
+	}
+	return s.Name
+}
+
+// Loader loads the configuration from layers, where later layers override
+// earlier ones: the defaults, the configuration file, the ConfigMap, the YAML
+// files of a conf.d directory in lexical order and environment variables with
+// the prefix EnvPrefix. Maps are merged, all other values are replaced. ${VAR} in
+// the files is expanded from the environment.
+type Loader struct {
+	// File is the configuration file, optional
+	File string
+	// ConfigMap is read via the Kubernetes API, optional. It has to be
+	// started before the first snapshot.
+	ConfigMap *ConfigMap
+	// Dir is the conf.d directory, optional
+	Dir string
+	// Environ is the environment, defaults to os.Environ()
+	Environ []string
+}
+
+// layer is the raw content of a single source
+type layer struct {
+	name    string
+	content []byte
+	// env is set for a layer from an environment variable
+	env bool
+	// builtin is set for the defaults, which have no meaningful lines
+	builtin bool
+}
+
+// Snapshot is the raw content of all layers at a point in time
+type Snapshot struct {
+	layers  []layer
+	environ map[string]string
+}
+
+// Snapshot reads all layers
+func (l *Loader) Snapshot() (*Snapshot, error) {
+	environ := l.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	s := &Snapshot{
+		layers:  []layer{{name: "default", content: defaults, builtin: true}},
+		environ: make(map[string]string, len(environ)),
+	}
+	for _, e := range environ {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 2 {
+			s.environ[kv[0]] = kv[1]
+		}
+	}
+
+	if l.File != "" {
+		err := s.readFile(l.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.ConfigMap != nil {
+		content, err := l.ConfigMap.read()
+		if err != nil {
+			return nil, err
+		}
+		s.layers = append(s.layers, layer{name: l.ConfigMap.String(), content: content})
+	}
+	if l.Dir != "" {
+		entries, err := ioutil.ReadDir(l.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory %v, %w", l.Dir, err)
+		}
+		// ReadDir sorts by name
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") || ext != ".yaml" && ext != ".yml" {
+				continue
+			}
+			err := s.readFile(filepath.Join(l.Dir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var names []string
+	for name := range s.environ {
+		if strings.HasPrefix(name, EnvPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.layers = append(s.layers, layer{name: name, content: []byte(s.environ[name]), env: true})
+	}
+	return s, nil
+}
+
+// readFile adds the file as layer
+func (s *Snapshot) readFile(name string) error {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %v, %w", name, err)
+	}
+	s.layers = append(s.layers, layer{name: name, content: content})
+	return nil
+}
+
+// changed returns a channel signaling changes of the layers, which are
+// not detected by polling
+func (l *Loader) changed() <-chan struct{} {
+	if l.ConfigMap == nil {
+		return nil
+	}
+	return l.ConfigMap.changed
+}
+
+// Hash returns a hash over all layers
+func (s *Snapshot) Hash() [sha256.Size]byte {
+	h := sha256.New()
+	for _, l := range s.layers {
+		fmt.Fprintf(h, "%v\x00%v\x00", l.name, len(l.content))
+		h.Write(l.content)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Parse merges the layers and validates the result like Validate
+func (s *Snapshot) Parse() (*Config, Issues) {
+	root, v := s.merge(true)
+	if len(v.issues.Errors()) > 0 {
+		return &Config{}, v.issues
+	}
+	cfg := v.validate(root)
+	return cfg, v.issues
+}
+
+// Print writes the merged configuration as YAML annotated with the source of
+// every value. Variables are not expanded, so that secrets are not printed.
+func (s *Snapshot) Print(w io.Writer) error {
+	root, v := s.merge(false)
+	err := v.issues.Err()
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return nil
+	}
+	annotate(root, "", v.sources)
+	enc := yamlv3.NewEncoder(w)
+	enc.SetIndent(2)
+	err = enc.Encode(root)
+	if err != nil {
+		return fmt.Errorf("failed to encode config, %w", err)
+	}
+	return enc.Close()
+}
+
+// merge merges the layers and expands the variables in the files, if expand
+// is set. The issues of the returned validator contain errors, if a layer can
+// not be parsed or a variable is undefined.
+func (s *Snapshot) merge(expand bool) (*yamlv3.Node, *validator) {
+	v := &validator{sources: make(map[string]Source)}
+	var root *yamlv3.Node
+	for _, l := range s.layers {
+		var node *yamlv3.Node
+		if l.env {
+			node = s.envNode(l, v)
+		} else {
+			node = s.fileNode(l, v, expand)
+		}
+		if node == nil {
+			continue
+		}
+		if root == nil {
+			root = node
+		} else {
+			root = mergeNode(root, node, "", v.sources)
+		}
+		record(node, "", l.name, v.sources)
+		if l.builtin {
+			for p := range v.sources {
+				v.sources[p] = Source{Name: l.name}
+			}
+		}
+	}
+	return root, v
+}
+
+// fileNode parses a file layer after expanding the variables, if expand is
+// set. Returns nil for empty files.
+func (s *Snapshot) fileNode(l layer, v *validator, expand bool) *yamlv3.Node {
+	content := s.expand(l, v)
+	if !expand {
+		content = l.content
+	}
+
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(content, &doc)
+	if err != nil {
+		v.issues = append(v.issues, Issue{Source: l.name, Message: err.Error()})
+		return nil
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	node := doc.Content[0]
+	if node.Kind != yamlv3.MappingNode {
+		v.issues = append(v.issues, Issue{Source: l.name, Line: node.Line, Message: "configuration is not a map"})
+		return nil
+	}
+	return node
+}
+
+// expand returns the content of the file layer with the variables replaced
+// by their values. Undefined variables are reported with their line.
+func (s *Snapshot) expand(l layer, v *validator) []byte {
+	var b bytes.Buffer
+	last := 0
+	for _, m := range variableRegexp.FindAllSubmatchIndex(l.content, -1) {
+		b.Write(l.content[last:m[0]])
+		last = m[1]
+		if bytes.HasPrefix(l.content[m[0]:], []byte("$$")) {
+			b.Write(l.content[m[0]+1 : m[1]])
+			continue
+		}
+		name := string(l.content[m[2]:m[3]])
+		value, ok := s.environ[name]
+		if !ok {
+			line := bytes.Count(l.content[:m[0]], []byte("\n")) + 1
+			v.issues = append(v.issues, Issue{Source: l.name, Line: line, Message: fmt.Sprintf("undefined variable %v", name)})
+		}
+		b.WriteString(value)
+	}
+	b.Write(l.content[last:])
+	return b.Bytes()
+}
+
+// envNode converts an environment variable into a node. The name is mapped
+// to the path of the field, e.g. K8S_RESTARTER_INCLUDE_ENABLED to
+// include.enabled, and the value is parsed as YAML. Variables not matching a
+// field are ignored, since they can also be set by Kubernetes for services.
+func (s *Snapshot) envNode(l layer, v *validator) *yamlv3.Node {
+	keys := fieldPath(strings.Split(strings.TrimPrefix(l.name, EnvPrefix), "_"))
+	if keys == nil {
+		return nil
+	}
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(l.content, &doc)
+	if err != nil {
+		v.issues = append(v.issues, Issue{Source: l.name, Path: strings.Join(keys, "."), Message: err.Error()})
+		return nil
+	}
+	value := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null"}
+	if len(doc.Content) > 0 {
+		value = doc.Content[0]
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = &yamlv3.Node{
+			Kind:    yamlv3.MappingNode,
+			Tag:     "!!map",
+			Content: []*yamlv3.Node{{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: keys[i]}, value},
+		}
+	}
+	return value
+}
+
+var reflectConfig = reflect.TypeOf(Config{})
+
+// fieldByUpperName returns the field of the struct type, whose JSON name in
+// upper case is the name
+func fieldByUpperName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || tag == "" || tag == "-" {
+			continue
+		}
+		if strings.ToUpper(tag) == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldPath maps the upper case parts of an environment variable to the
+// JSON names of the fields of Config. Returns nil, if there is no such field.
+func fieldPath(parts []string) []string {
+	t := reflectConfig
+	var keys []string
+	for _, part := range parts {
+		f, ok := fieldByUpperName(t, part)
+		if !ok {
+			return nil
+		}
+		keys = append(keys, strings.Split(f.Tag.Get("json"), ",")[0])
+		t = f.Type
+	}
+	return keys
+}
+
+// record records the source of all children of the node
+func record(node *yamlv3.Node, path, name string, sources map[string]Source) {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			p := joinPath(path, key.Value)
+			sources[p] = Source{Name: name, Line: key.Line}
+			record(node.Content[i+1], p, name, sources)
+		}
+	case yamlv3.SequenceNode:
+		for i, item := range node.Content {
+			p := fmt.Sprintf("%v[%v]", path, i)
+			sources[p] = Source{Name: name, Line: item.Line}
+			record(item, p, name, sources)
+		}
+	}
+}
+
+// mergeNode merges src into dst and returns the result. Maps are merged, all
+// other values are replaced. The sources of the children of replaced values
+// are removed.
+func mergeNode(dst, src *yamlv3.Node, path string, sources map[string]Source) *yamlv3.Node {
+	if dst.Kind != yamlv3.MappingNode || src.Kind != yamlv3.MappingNode {
+		forget(path, sources)
+		return src
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				dst.Content[j+1] = mergeNode(dst.Content[j+1], value, joinPath(path, key.Value), sources)
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+	return dst
+}
+
+// forget removes the sources of the children of the path
+func forget(path string, sources map[string]Source) {
+	for p := range sources {
+		if strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			delete(sources, p)
+		}
+	}
+}
+
+// annotate sets the source of every value as line comment and removes all
+// other comments
+func annotate(node *yamlv3.Node, path string, sources map[string]Source) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			p := joinPath(path, key.Value)
+			key.HeadComment, key.LineComment, key.FootComment = "", "", ""
+			annotate(value, p, sources)
+			if value.Kind == yamlv3.ScalarNode || len(value.Content) == 0 {
+				value.LineComment = "# " + sources[p].String()
+			}
+		}
+	case yamlv3.SequenceNode:
+		for i, item := range node.Content {
+			p := fmt.Sprintf("%v[%v]", path, i)
+			annotate(item, p, sources)
+			if item.Kind == yamlv3.ScalarNode {
+				item.LineComment = "# " + sources[p].String()
+			}
+		}
+	}
+}
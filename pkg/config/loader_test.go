@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoader_Snapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		dir     map[string]string
+		environ []string
+		check   func(*Config) bool
+		wantErr string
+	}{
+		{
+			name:  "file only",
+			file:  "reconcilationInterval: 1m\nrestartInterval: 10m",
+			check: func(c *Config) bool { return c.RestartInterval == 10*time.Minute && c.LivenessIntervals == 5 },
+		},
+		{
+			name: "directory in lexical order",
+			file: "reconcilationInterval: 1m\nrestartInterval: 10m",
+			dir: map[string]string{
+				"20-b.yaml":  "restartInterval: 30m\ninclude:\n  selectors:\n  - namespace: b",
+				"10-a.yml":   "restartInterval: 20m\ninclude:\n  enabled: true\n  selectors:\n  - namespace: a",
+				".10-c.yaml": "restartInterval: 1s",
+				"30-d.txt":   "restartInterval: 1s",
+				"40-e.yaml":  "",
+			},
+			check: func(c *Config) bool {
+				return c.RestartInterval == 30*time.Minute && c.Include.Enabled &&
+					len(c.Include.Selectors) == 1 && c.Include.Selectors[0].Namespace == "b"
+			},
+		},
+		{
+			name:    "environment overrides files",
+			file:    "reconcilationInterval: 1m\nrestartInterval: 10m\ninclude:\n  selectors:\n  - namespace: a",
+			environ: []string{"K8S_RESTARTER_RESTARTINTERVAL=1h", "K8S_RESTARTER_INCLUDE_ENABLED=true", "K8S_RESTARTER_SERVICE_HOST=10.0.0.1"},
+			check: func(c *Config) bool {
+				return c.RestartInterval == time.Hour && c.Include.Enabled && c.Include.Selectors[0].Namespace == "a"
+			},
+		},
+		{
+			name:    "variables",
+			file:    "reconcilationInterval: ${INTERVAL}\nrestartInterval: 10m\nnotifications:\n  webhooks:\n  - url: https://example.com/$${TOKEN}",
+			environ: []string{"INTERVAL=5m"},
+			check: func(c *Config) bool {
+				return c.ReconcilationInterval == 5*time.Minute && c.Notifications.Webhooks[0].URL == "https://example.com/${TOKEN}"
+			},
+		},
+		{
+			name:    "undefined variable",
+			file:    "reconcilationInterval: 1m\nrestartInterval: ${INTERVAL}",
+			wantErr: "config.yaml:2: undefined variable INTERVAL",
+		},
+		{
+			name:    "repeated undefined variable",
+			file:    "reconcilationInterval: ${INTERVAL}\nrestartInterval: ${INTERVAL}",
+			wantErr: "config.yaml:2: undefined variable INTERVAL",
+		},
+		{
+			name:    "invalid environment",
+			file:    "reconcilationInterval: 1m\nrestartInterval: 10m",
+			environ: []string{"K8S_RESTARTER_RESTARTINTERVAL=-1m"},
+			wantErr: "K8S_RESTARTER_RESTARTINTERVAL: restartInterval:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			loader := &Loader{File: filepath.Join(tmp, "config.yaml"), Environ: tt.environ}
+			if loader.Environ == nil {
+				loader.Environ = []string{}
+			}
+			err := ioutil.WriteFile(loader.File, []byte(tt.file), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.dir != nil {
+				loader.Dir = filepath.Join(tmp, "conf.d")
+				writeFiles(t, loader.Dir, tt.dir)
+			}
+			snapshot, err := loader.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, issues := snapshot.Parse()
+			err = issues.Err()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("Parse() = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestSnapshot_Print(t *testing.T) {
+	tmp := t.TempDir()
+	loader := &Loader{
+		File:    filepath.Join(tmp, "config.yaml"),
+		Dir:     filepath.Join(tmp, "conf.d"),
+		Environ: []string{"K8S_RESTARTER_RESTARTINTERVAL=1h", "WEBHOOK_URL=https://example.com/secret"},
+	}
+	err := ioutil.WriteFile(loader.File, []byte("reconcilationInterval: 1m\nrestartInterval: 10m\nnotifications:\n  webhooks:\n  - url: ${WEBHOOK_URL}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, loader.Dir, map[string]string{"10-include.yaml": "include:\n  enabled: true\n  selectors:\n  - namespace: a\n"})
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = snapshot.Print(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"livenessIntervals: 5 # default",
+		"reconcilationInterval: 1m # " + loader.File + ":1",
+		"restartInterval: 1h # K8S_RESTARTER_RESTARTINTERVAL",
+		"namespace: a # " + filepath.Join(loader.Dir, "10-include.yaml") + ":4",
+		"url: ${WEBHOOK_URL} # " + loader.File + ":5",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Print() = %v, want %v", b.String(), want)
+		}
+	}
+	if strings.Contains(b.String(), "secret") {
+		t.Errorf("Print() = %v, want unexpanded variables", b.String())
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
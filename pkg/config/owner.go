@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OwnerSelector selects apps by their owner references. Kind and APIVersion
+// are glob patterns, empty fields match everything.
+type OwnerSelector struct {
+	// Kind of the owner like Prometheus, matched case insensitive
+	Kind string `json:"kind"`
+	// APIVersion of the owner like monitoring.coreos.com/v1
+	APIVersion string `json:"apiVersion"`
+	// Controller selects only controller owners, if true, or only other
+	// owners, if false
+	Controller *bool `json:"controller"`
+}
+
+// validate checks the glob patterns
+func (o OwnerSelector) validate() error {
+	for _, p := range []string{o.Kind, o.APIVersion} {
+		_, err := path.Match(p, "")
+		if err != nil {
+			return fmt.Errorf("invalid owner pattern %q, %w", p, err)
+		}
+	}
+	return nil
+}
+
+// MatchesAny checks if any of the owner references matches the selector
+func (o OwnerSelector) MatchesAny(refs []metav1.OwnerReference) bool {
+	for _, ref := range refs {
+		if o.Kind != "" {
+			if ok, _ := path.Match(strings.ToLower(o.Kind), strings.ToLower(ref.Kind)); !ok {
+				continue
+			}
+		}
+		if o.APIVersion != "" {
+			if ok, _ := path.Match(o.APIVersion, ref.APIVersion); !ok {
+				continue
+			}
+		}
+		if o.Controller != nil && *o.Controller != isController(ref) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func isController(ref metav1.OwnerReference) bool {
+	return ref.Controller != nil && *ref.Controller
+}
+
+// hasControllerOwner checks if one of the owner references is a controller
+func hasControllerOwner(refs []metav1.OwnerReference) bool {
+	for _, ref := range refs {
+		if isController(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsOwners checks if the selector explicitly selects apps with a
+// controller owner
+func (s *Selector) selectsOwners() bool {
+	if s == nil {
+		return false
+	}
+	return len(s.Owners) > 0 || s.HasControllerOwner != nil && *s.HasControllerOwner
+}
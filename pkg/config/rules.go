@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ActionRestart restarts the matching apps, the default
+	ActionRestart = "restart"
+	// ActionIgnore never restarts the matching apps
+	ActionIgnore = "ignore"
+
+	// RuleControllerOwned is the name of the implicit rule ignoring apps
+	// with a controller owner
+	RuleControllerOwned = "controller-owned"
+)
+
+// Rule decides how the matching apps are restarted. The first matching rule
+// of the configuration decides.
+type Rule struct {
+	Name string `json:"name"`
+	// Selector selects the apps, all apps if nil
+	Selector *Selector `json:"selector"`
+	// Action is either restart or ignore, defaults to restart
+	Action                string        `json:"action"`
+	RestartInterval       time.Duration `json:"-"`
+	RestartIntervalHelper string        `json:"restartInterval"`
+	// Schedule restarts the apps at fixed times instead of the interval
+	Schedule *Schedule `json:"schedule"`
+	// Window restricts the restarts to a time of the day
+	Window *Window `json:"window"`
+	// Strategy limits the restarts of all apps of the rule
+	Strategy *Strategy `json:"strategy"`
+}
+
+// Strategy limits how many apps of a rule are restarted. Rules with the same
+// name share their limits.
+type Strategy struct {
+	// MaxConcurrent is the number of apps rolling out at the same time,
+	// unlimited if 0
+	MaxConcurrent int `json:"maxConcurrent"`
+	// MaxRestarts is the number of restarts within the budget period,
+	// unlimited if 0
+	MaxRestarts        int           `json:"maxRestarts"`
+	BudgetPeriod       time.Duration `json:"-"`
+	BudgetPeriodHelper string        `json:"budgetPeriod"`
+}
+
+// NextRestart returns the next restart of an app of the rule last restarted,
+// or created, at the given time. Returns the zero time, if the schedule never
+// matches.
+func (r Rule) NextRestart(last time.Time) time.Time {
+	if r.Schedule != nil {
+		return r.Schedule.Next(last)
+	}
+	return last.Add(r.RestartInterval)
+}
+
+// Window is a time of the day, e.g. 22:00 to 06:00
+type Window struct {
+	// Start and End in the format 15:04. The window spans midnight, if End
+	// is before Start.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// TimeZone is the IANA time zone of Start and End, defaults to UTC
+	TimeZone string `json:"timeZone"`
+
+	compiled *compiledWindow
+}
+
+// compiledWindow holds the parsed start, end and location of a window
+type compiledWindow struct {
+	// start and end as minutes of the day
+	start, end int
+	location   *time.Location
+}
+
+// compile parses start, end and the time zone of the window
+func (w *Window) compile() (*compiledWindow, error) {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start %q, %w", w.Start, err)
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end %q, %w", w.End, err)
+	}
+	loc, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q, %w", w.TimeZone, err)
+	}
+	return &compiledWindow{
+		start:    start.Hour()*60 + start.Minute(),
+		end:      end.Hour()*60 + end.Minute(),
+		location: loc,
+	}, nil
+}
+
+// Contains checks if the time is within the window. Invalid windows contain
+// nothing.
+func (w *Window) Contains(t time.Time) bool {
+	c := w.compiled
+	if c == nil {
+		var err error
+		c, err = w.compile()
+		if err != nil {
+			return false
+		}
+	}
+	t = t.In(c.location)
+	m := t.Hour()*60 + t.Minute()
+	if c.start <= c.end {
+		return c.start <= m && m < c.end
+	}
+	return m >= c.start || m < c.end
+}
+
+// Match returns the first rule matching the target. Without rules, the rules
+// are derived from the include and exclude matchers. Returns false, if no rule
+// matches. Returns an error, if a selector can not be evaluated before a rule
+// matches, so that the app is skipped instead of matching a later rule. Only
+// include selectors, whose namespace labels are unknown, do not match instead.
+func (c *Config) Match(t Target) (Rule, bool, error) {
+	for i, r := range c.effectiveRules() {
+		if r.Name == "" && len(c.Rules) > 0 {
+			r.Name = fmt.Sprintf("rules[%v]", i)
+		}
+		if r.Selector != nil {
+			ok, err := r.Selector.match(t)
+			if errors.Is(err, ErrNamespaceUnknown) && len(c.Rules) == 0 && r.Action != ActionIgnore {
+				continue
+			}
+			if err != nil {
+				return Rule{}, false, fmt.Errorf("failed to evaluate selector of rule %v, %w", r.Name, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		if r.Action == "" {
+			r.Action = ActionRestart
+		}
+		if r.Action == ActionRestart && r.RestartInterval == 0 && r.Schedule == nil {
+			r.RestartInterval = c.RestartInterval
+		}
+		// Apps managed by operators are only restarted on explicit request
+		if r.Action == ActionRestart && c.skipControllerOwned() &&
+			hasControllerOwner(t.OwnerReferences) && !r.Selector.selectsOwners() {
+			return Rule{Name: RuleControllerOwned, Action: ActionIgnore}, true, nil
+		}
+		return r, true, nil
+	}
+	return Rule{}, false, nil
+}
+
+// skipControllerOwned returns if apps with a controller owner are skipped
+func (c *Config) skipControllerOwned() bool {
+	return c.SkipControllerOwned == nil || *c.SkipControllerOwned
+}
+
+// effectiveRules returns the rules or, if there are none, the rules derived
+// from the include and exclude matchers
+func (c *Config) effectiveRules() []Rule {
+	if len(c.Rules) > 0 {
+		return c.Rules
+	}
+	var rules []Rule
+	if c.Exclude.Enabled {
+		for i := range c.Exclude.Selectors {
+			rules = append(rules, Rule{Name: "exclude", Selector: &c.Exclude.Selectors[i], Action: ActionIgnore})
+		}
+	}
+	if !c.Include.Enabled {
+		return append(rules, Rule{})
+	}
+	for i := range c.Include.Selectors {
+		rules = append(rules, Rule{Name: "include", Selector: &c.Include.Selectors[i]})
+	}
+	return rules
+}
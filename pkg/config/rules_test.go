@@ -0,0 +1,425 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfig_Match(t *testing.T) {
+	controller := true
+	tests := []struct {
+		name     string
+		content  string
+		target   Target
+		want     string
+		action   string
+		interval time.Duration
+		ok       bool
+		wantErr  bool
+	}{
+		{
+			name:     "Without rules and matchers",
+			content:  "reconcilationInterval: 1m\nrestartInterval: 24h\n",
+			target:   Target{Namespace: "shop"},
+			action:   ActionRestart,
+			interval: 24 * time.Hour,
+			ok:       true,
+		},
+		{
+			name: "Legacy exclude before include",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+include:
+  enabled: true
+  selectors:
+    - namespace: shop
+exclude:
+  enabled: true
+  selectors:
+    - name: redis
+`,
+			target: Target{Namespace: "shop", Name: "redis"},
+			want:   "exclude",
+			action: ActionIgnore,
+			ok:     true,
+		},
+		{
+			name: "Legacy not included",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+include:
+  enabled: true
+  selectors:
+    - namespace: shop
+`,
+			target: Target{Namespace: "other"},
+		},
+		{
+			name: "First matching rule",
+			content: `reconcilationInterval: 1m
+rules:
+  - name: databases
+    selector:
+      matchLabels:
+        tier: database
+    action: ignore
+  - selector:
+      namespace: prod-*
+    restartInterval: 168h
+  - restartInterval: 24h
+`,
+			target:   Target{Namespace: "prod-shop", Labels: map[string]string{"tier": "web"}},
+			want:     "rules[1]",
+			action:   ActionRestart,
+			interval: 168 * time.Hour,
+			ok:       true,
+		},
+		{
+			name:    "Controller owned",
+			content: "reconcilationInterval: 1m\nrestartInterval: 24h\n",
+			target:  Target{Namespace: "db", OwnerReferences: []metav1.OwnerReference{{Kind: "Postgres", Controller: &controller}}},
+			want:    RuleControllerOwned,
+			action:  ActionIgnore,
+			ok:      true,
+		},
+		{
+			name:     "Controller owned not skipped",
+			content:  "reconcilationInterval: 1m\nrestartInterval: 24h\nskipControllerOwned: false\n",
+			target:   Target{Namespace: "db", OwnerReferences: []metav1.OwnerReference{{Kind: "Postgres", Controller: &controller}}},
+			action:   ActionRestart,
+			interval: 24 * time.Hour,
+			ok:       true,
+		},
+		{
+			name: "Controller owned explicitly selected",
+			content: `reconcilationInterval: 1m
+rules:
+  - selector:
+      owners:
+        - kind: postgres
+          apiVersion: acid.zalan.do/*
+    restartInterval: 168h
+`,
+			target:   Target{Namespace: "db", OwnerReferences: []metav1.OwnerReference{{Kind: "Postgres", APIVersion: "acid.zalan.do/v1", Controller: &controller}}},
+			want:     "rules[0]",
+			action:   ActionRestart,
+			interval: 168 * time.Hour,
+			ok:       true,
+		},
+		{
+			name: "Legacy include with unknown namespace labels does not match",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+include:
+  enabled: true
+  selectors:
+    - namespaceSelector:
+        matchExpressions:
+          - key: environment
+            operator: NotIn
+            values: [production]
+`,
+			target: Target{Namespace: "shop", NamespaceUnknown: true},
+		},
+		{
+			name: "Legacy exclude with unknown namespace labels fails",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+exclude:
+  enabled: true
+  selectors:
+    - namespaceSelector:
+        matchExpressions:
+          - key: environment
+            operator: DoesNotExist
+`,
+			target:  Target{Namespace: "shop", NamespaceUnknown: true},
+			wantErr: true,
+		},
+		{
+			name: "Rule with unknown namespace labels fails",
+			content: `reconcilationInterval: 1m
+rules:
+  - selector:
+      namespaceSelector:
+        matchLabels:
+          tier: database
+    action: ignore
+  - restartInterval: 1h
+`,
+			target:  Target{Namespace: "db", NamespaceUnknown: true},
+			wantErr: true,
+		},
+		{
+			name: "Failing expression does not fall through",
+			content: `reconcilationInterval: 1m
+rules:
+  - selector:
+      expression: object.spec.paused
+    action: ignore
+  - restartInterval: 1h
+`,
+			target:  Target{Namespace: "shop", Kind: "Deployment", Name: "web", Object: &appsv1.Deployment{}},
+			wantErr: true,
+		},
+		{
+			name: "Unknown namespace labels without namespace selector",
+			content: `reconcilationInterval: 1m
+rules:
+  - selector:
+      namespace: kube-system
+      namespaceSelector:
+        matchLabels:
+          tier: system
+    action: ignore
+  - restartInterval: 1h
+`,
+			target:   Target{Namespace: "shop", NamespaceUnknown: true},
+			want:     "rules[1]",
+			action:   ActionRestart,
+			interval: time.Hour,
+			ok:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule, ok, err := cfg.Match(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Match() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.ok {
+				t.Fatalf("Match() ok = %v, want %v", ok, tt.ok)
+			}
+			if rule.Name != tt.want || rule.Action != tt.action || rule.RestartInterval != tt.interval {
+				t.Errorf("Match() = %v/%v/%v, want %v/%v/%v", rule.Name, rule.Action, rule.RestartInterval, tt.want, tt.action, tt.interval)
+			}
+		})
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector Selector
+		target   Target
+		want     bool
+	}{
+		{
+			name:     "Namespace match",
+			selector: Selector{Namespace: "namespace"},
+			target:   Target{Namespace: "namespace"},
+			want:     true,
+		},
+		{
+			name:     "Namespace does not match",
+			selector: Selector{Namespace: "namespace"},
+			target:   Target{Namespace: "other namespace"},
+			want:     false,
+		},
+		{
+			name:     "Labels match",
+			selector: Selector{MatchLabels: map[string]string{"label1": "label1", "label2": "label2"}},
+			target:   Target{Labels: map[string]string{"label1": "label1", "label2": "label2", "label3": "label3"}},
+			want:     true,
+		},
+		{
+			name:     "Labels does not match",
+			selector: Selector{MatchLabels: map[string]string{"label1": "label1", "label2": "label2"}},
+			target:   Target{Labels: map[string]string{"label1": "label1", "label3": "label3"}},
+			want:     false,
+		},
+		{
+			name: "Namespace pattern with exception",
+			selector: Selector{
+				Namespace: "prod-*",
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"database"}},
+				},
+			},
+			target: Target{Namespace: "prod-shop", Labels: map[string]string{"tier": "web"}},
+			want:   true,
+		},
+		{
+			name: "Expression does not match",
+			selector: Selector{
+				Namespace: "prod-*",
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"database"}},
+				},
+			},
+			target: Target{Namespace: "prod-shop", Labels: map[string]string{"tier": "database"}},
+			want:   false,
+		},
+		{
+			name:     "Kind and name regex match",
+			selector: Selector{NamespaceRegex: "name.*", Kinds: []string{"statefulset"}, NameRegex: "redis-[0-9]+"},
+			target:   Target{Namespace: "namespace", Kind: "StatefulSet", Name: "redis-0"},
+			want:     true,
+		},
+		{
+			name:     "Kind does not match",
+			selector: Selector{Kinds: []string{"StatefulSet"}, Name: "redis*"},
+			target:   Target{Namespace: "namespace", Kind: "Deployment", Name: "redis"},
+			want:     false,
+		},
+		{
+			name:     "Namespace labels match",
+			selector: Selector{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "staging"}}},
+			target:   Target{Namespace: "shop-staging", NamespaceLabels: map[string]string{"environment": "staging", "team": "shop"}},
+			want:     true,
+		},
+		{
+			name:     "Namespace labels do not match",
+			selector: Selector{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "staging"}}},
+			target:   Target{Namespace: "shop-production", NamespaceLabels: map[string]string{"environment": "production"}},
+			want:     false,
+		},
+		{
+			name:     "Image matches",
+			selector: Selector{Images: []ImageSelector{{Repository: "library/alpine", Tag: "3.*"}}},
+			target:   Target{Images: []string{"alpine:3.15", "ghcr.io/shop/web:1.2"}},
+			want:     true,
+		},
+		{
+			name:     "Image does not match",
+			selector: Selector{Images: []ImageSelector{{Registry: "docker.io"}}},
+			target:   Target{Images: []string{"ghcr.io/shop/web:1.2"}},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Matches(tt.target); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindow_Contains(t *testing.T) {
+	tests := []struct {
+		name   string
+		window Window
+		time   string
+		want   bool
+	}{
+		{"Inside", Window{Start: "01:00", End: "04:00"}, "2022-06-01T02:00:00Z", true},
+		{"End is exclusive", Window{Start: "01:00", End: "04:00"}, "2022-06-01T04:00:00Z", false},
+		{"Across midnight", Window{Start: "22:00", End: "06:00"}, "2022-06-01T23:30:00Z", true},
+		{"Outside across midnight", Window{Start: "22:00", End: "06:00"}, "2022-06-01T12:00:00Z", false},
+		{"Time zone", Window{Start: "01:00", End: "04:00", TimeZone: "Europe/Berlin"}, "2022-06-01T00:30:00Z", true},
+		{"Invalid", Window{Start: "1 o'clock", End: "04:00"}, "2022-06-01T02:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tt.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.window.Contains(now); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindow_compiled(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`reconcilationInterval: 1m
+restartInterval: 24h
+rules:
+  - window:
+      start: "01:00"
+      end: "04:00"
+      timeZone: Europe/Berlin
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := cfg.Rules[0].Window
+	if w.compiled == nil {
+		t.Fatal("window not compiled by the validation")
+	}
+	if w.compiled.start != 60 || w.compiled.end != 240 || w.compiled.location.String() != "Europe/Berlin" {
+		t.Errorf("compiled = %v/%v/%v, want 60/240/Europe/Berlin", w.compiled.start, w.compiled.end, w.compiled.location)
+	}
+	if !w.Contains(time.Date(2022, 6, 1, 0, 30, 0, 0, time.UTC)) {
+		t.Errorf("Contains() = false, want true")
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		time     string
+		want     string
+	}{
+		{"Every minute", Schedule{Cron: "* * * * *"}, "2022-06-01T12:00:30Z", "2022-06-01T12:01:00Z"},
+		{"Strictly after", Schedule{Cron: "0 3 * * *"}, "2022-06-01T03:00:00Z", "2022-06-02T03:00:00Z"},
+		{"Day of week", Schedule{Cron: "0 3 * * 0"}, "2022-06-01T12:00:00Z", "2022-06-05T03:00:00Z"},
+		{"Sunday as 7", Schedule{Cron: "0 3 * * 7"}, "2022-06-01T12:00:00Z", "2022-06-05T03:00:00Z"},
+		{"Steps", Schedule{Cron: "*/15 * * * *"}, "2022-06-01T12:01:00Z", "2022-06-01T12:15:00Z"},
+		{"Lists and ranges", Schedule{Cron: "30 1,22-23 * * *"}, "2022-06-01T02:00:00Z", "2022-06-01T22:30:00Z"},
+		{"Day of month", Schedule{Cron: "0 0 1 * *"}, "2022-06-01T12:00:00Z", "2022-07-01T00:00:00Z"},
+		{"Day of month or week", Schedule{Cron: "0 0 15 * 1"}, "2022-06-01T12:00:00Z", "2022-06-06T00:00:00Z"},
+		{"Leap day", Schedule{Cron: "0 0 29 2 *"}, "2022-06-01T12:00:00Z", "2024-02-29T00:00:00Z"},
+		{"Time zone", Schedule{Cron: "0 3 * * *", TimeZone: "Europe/Berlin"}, "2022-06-01T12:00:00Z", "2022-06-02T01:00:00Z"},
+		{"Never", Schedule{Cron: "0 0 30 2 *"}, "2022-06-01T12:00:00Z", ""},
+		{"Invalid", Schedule{Cron: "0 3 * *"}, "2022-06-01T12:00:00Z", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tt.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want time.Time
+			if tt.want != "" {
+				want, err = time.Parse(time.RFC3339, tt.want)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := tt.schedule.Next(now); !got.Equal(want) {
+				t.Errorf("Next() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_parseCronField(t *testing.T) {
+	tests := []struct {
+		field   string
+		want    uint64
+		wantErr bool
+	}{
+		{"*", 0x3f, false},
+		{"2", 1 << 2, false},
+		{"1-3", 0xe, false},
+		{"*/2", 0x15, false},
+		{"1/2", 0x2a, false},
+		{"0,5", 0x21, false},
+		{"6", 0, true},
+		{"3-1", 0, true},
+		{"*/0", 0, true},
+		{"a", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parseCronField(tt.field, 0, 5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCronField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseCronField() = %b, want %b", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleHorizon limits the search for the next time of a schedule, so that
+// schedules never matching, e.g. on February 30, end
+const scheduleHorizon = 5 * 366
+
+// Schedule are the times apps are restarted at, e.g. every Sunday at 03:00
+type Schedule struct {
+	// Cron is the expression of minute, hour, day of month, month and day of
+	// week, e.g. "0 3 * * 0". Fields are *, numbers, ranges like 1-5, lists
+	// like 1,3 and steps like */15.
+	Cron string `json:"cron"`
+	// TimeZone is the IANA time zone of the expression, defaults to UTC
+	TimeZone string `json:"timeZone"`
+
+	compiled *compiledSchedule
+}
+
+// compiledSchedule holds the parsed fields and location of a schedule
+type compiledSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday are set, if the day of month or of week is *
+	anyDay, anyWeekday bool
+	location           *time.Location
+}
+
+// compile parses the cron expression and the time zone of the schedule
+func (s *Schedule) compile() (*compiledSchedule, error) {
+	fields := strings.Fields(s.Cron)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron %q, expected 5 fields, got %v", s.Cron, len(fields))
+	}
+	c := &compiledSchedule{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	var err error
+	for i, f := range []struct {
+		name     string
+		bits     *uint64
+		min, max int
+	}{
+		{"minute", &c.minutes, 0, 59},
+		{"hour", &c.hours, 0, 23},
+		{"day of month", &c.days, 1, 31},
+		{"month", &c.months, 1, 12},
+		{"day of week", &c.weekdays, 0, 7},
+	} {
+		*f.bits, err = parseCronField(fields[i], f.min, f.max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v %q, %w", f.name, fields[i], err)
+		}
+	}
+	// Sunday is 0 or 7
+	if c.weekdays&(1<<7) != 0 {
+		c.weekdays |= 1
+	}
+	c.location, err = time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q, %w", s.TimeZone, err)
+	}
+	return c, nil
+}
+
+// parseCronField parses a comma separated list of *, numbers and ranges with
+// optional steps into a bit set
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		r, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			r = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+		}
+		start, end := min, max
+		if r != "*" {
+			bounds := strings.SplitN(r, "-", 2)
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			} else if step > 1 {
+				// A step without a range, e.g. 5/15, runs to the maximum
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q out of range %v-%v", r, min, max)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time of the schedule after t. Returns the zero time
+// for invalid schedules or schedules never matching.
+func (s *Schedule) Next(t time.Time) time.Time {
+	c := s.compiled
+	if c == nil {
+		var err error
+		c, err = s.compile()
+		if err != nil {
+			return time.Time{}
+		}
+	}
+	t = t.In(c.location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.location)
+	for i := 0; i < scheduleHorizon; i++ {
+		d := day.AddDate(0, 0, i)
+		if !c.matchDay(d) {
+			continue
+		}
+		for h := 0; h < 24; h++ {
+			if c.hours&(1<<uint(h)) == 0 {
+				continue
+			}
+			for m := 0; m < 60; m++ {
+				if c.minutes&(1<<uint(m)) == 0 {
+					continue
+				}
+				next := time.Date(d.Year(), d.Month(), d.Day(), h, m, 0, 0, c.location)
+				if next.After(t) {
+					return next
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay checks the month, day of month and day of week of a day. Like by
+// cron, either day has to match, if both are restricted.
+func (c *compiledSchedule) matchDay(d time.Time) bool {
+	if c.months&(1<<uint(d.Month())) == 0 {
+		return false
+	}
+	day := c.days&(1<<uint(d.Day())) != 0
+	weekday := c.weekdays&(1<<uint(d.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Kinds are the kinds of apps which can be selected
+var Kinds = []string{"Deployment", "StatefulSet", "DaemonSet"}
+
+// ErrNamespaceUnknown is returned, if a namespace selector can not be
+// evaluated, since the labels of the namespace are unknown
+var ErrNamespaceUnknown = errors.New("labels of the namespace are unknown")
+
+// Target is an app matched by selectors
+type Target struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Labels    map[string]string
+	// NamespaceLabels are the labels of the Namespace object of the app
+	NamespaceLabels map[string]string
+	// NamespaceUnknown is set, if the labels of the Namespace object are
+	// unknown, e.g. since it can not be read
+	NamespaceUnknown bool
+	// OwnerReferences of the app
+	OwnerReferences []metav1.OwnerReference
+	// Images of the containers and init containers of the app
+	Images []string
+	// Object is the app itself, used by the expressions
+	Object interface{}
+}
+
+// compiledSelector holds the parsed patterns of a selector
+type compiledSelector struct {
+	namespaceRegex  *regexp.Regexp
+	nameRegex       *regexp.Regexp
+	labels          labels.Selector
+	namespaceLabels labels.Selector
+	expression      cel.Program
+}
+
+// compile parses the patterns of the selector. Compiled expressions are
+// cached in programs, if not nil.
+func (s *Selector) compile(programs map[string]cel.Program) (*compiledSelector, error) {
+	c := &compiledSelector{}
+	var err error
+	if s.Namespace != "" {
+		_, err = path.Match(s.Namespace, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q, %w", s.Namespace, err)
+		}
+	}
+	if s.Name != "" {
+		_, err = path.Match(s.Name, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q, %w", s.Name, err)
+		}
+	}
+	if s.NamespaceRegex != "" {
+		c.namespaceRegex, err = regexp.Compile("^(?:" + s.NamespaceRegex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace regex %q, %w", s.NamespaceRegex, err)
+		}
+	}
+	if s.NameRegex != "" {
+		c.nameRegex, err = regexp.Compile("^(?:" + s.NameRegex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid name regex %q, %w", s.NameRegex, err)
+		}
+	}
+	c.labels, err = metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels:      s.MatchLabels,
+		MatchExpressions: s.MatchExpressions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector, %w", err)
+	}
+	c.namespaceLabels = labels.Everything()
+	if s.NamespaceSelector != nil {
+		c.namespaceLabels, err = metav1.LabelSelectorAsSelector(s.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector, %w", err)
+		}
+	}
+	for _, o := range s.Owners {
+		err = o.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, i := range s.Images {
+		err = i.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.Expression != "" {
+		c.expression, err = compileExpression(s.Expression, programs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Matches checks if the selector matches the app. Invalid selectors and
+// selectors, which can not be evaluated, never match.
+func (s *Selector) Matches(t Target) bool {
+	ok, err := s.match(t)
+	return ok && err == nil
+}
+
+// match checks if the selector matches the app. Invalid selectors never
+// match. It returns an error, if the selector can not be evaluated, e.g.
+// since the labels of the namespace are unknown or the expression fails.
+func (s *Selector) match(t Target) (bool, error) {
+	c := s.compiled
+	if c == nil {
+		var err error
+		c, err = s.compile(nil)
+		if err != nil {
+			return false, nil
+		}
+	}
+
+	if s.Namespace != "" {
+		if ok, _ := path.Match(s.Namespace, t.Namespace); !ok {
+			return false, nil
+		}
+	}
+	if c.namespaceRegex != nil && !c.namespaceRegex.MatchString(t.Namespace) {
+		return false, nil
+	}
+	if len(s.Kinds) > 0 {
+		found := false
+		for _, k := range s.Kinds {
+			found = found || strings.EqualFold(k, t.Kind)
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if s.Name != "" {
+		if ok, _ := path.Match(s.Name, t.Name); !ok {
+			return false, nil
+		}
+	}
+	if c.nameRegex != nil && !c.nameRegex.MatchString(t.Name) {
+		return false, nil
+	}
+	if !c.labels.Matches(labels.Set(t.Labels)) {
+		return false, nil
+	}
+	if len(s.Owners) > 0 {
+		found := false
+		for _, o := range s.Owners {
+			found = found || o.MatchesAny(t.OwnerReferences)
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if s.HasControllerOwner != nil && *s.HasControllerOwner != hasControllerOwner(t.OwnerReferences) {
+		return false, nil
+	}
+	if len(s.Images) > 0 {
+		found := false
+		for _, i := range s.Images {
+			if _, ok := i.MatchesAny(t.Images); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if c.expression != nil {
+		ok, err := evalExpression(c.expression, t)
+		if err != nil {
+			opsExpressionErrors.Inc()
+			return false, fmt.Errorf("failed to evaluate expression, %w", err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	// The namespace labels are checked last, so that unknown labels only
+	// fail selectors, which would match otherwise
+	if s.NamespaceSelector != nil {
+		if t.NamespaceUnknown {
+			return false, fmt.Errorf("%w, %v", ErrNamespaceUnknown, t.Namespace)
+		}
+		if !c.namespaceLabels.Matches(labels.Set(t.NamespaceLabels)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isEmpty returns true, if the selector matches all apps
+func (s *Selector) isEmpty() bool {
+	return s.Namespace == "" && s.NamespaceRegex == "" && len(s.Kinds) == 0 &&
+		s.Name == "" && s.NameRegex == "" &&
+		len(s.MatchLabels) == 0 && len(s.MatchExpressions) == 0 &&
+		s.NamespaceSelector == nil && len(s.Images) == 0 && s.Expression == "" &&
+		len(s.Owners) == 0 && s.HasControllerOwner == nil
+}
@@ -0,0 +1,566 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// notificationEvents are the event types known by the notifier
+var notificationEvents = []string{"started", "succeeded", "failed", "skipped-overdue", "postponed"}
+
+// Issue is a problem found during the validation of the configuration
+type Issue struct {
+	// Source is the file or environment variable of the offending field,
+	// empty for a single configuration
+	Source string `json:"source,omitempty"`
+	// Line in the source, 0 if unknown
+	Line int `json:"line,omitempty"`
+	// Path of the offending field, e.g. include.selectors[0].namespace
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+	// Warning marks issues which do not prevent the configuration from being
+	// used
+	Warning bool `json:"warning,omitempty"`
+}
+
+func (i Issue) String() string {
+	s := i.Message
+	if i.Path != "" {
+		s = i.Path + ": " + s
+	}
+	switch {
+	case i.Source != "" && i.Line > 0:
+		s = fmt.Sprintf("%v:%v: %v", i.Source, i.Line, s)
+	case i.Source != "":
+		s = fmt.Sprintf("%v: %v", i.Source, s)
+	case i.Line > 0:
+		s = fmt.Sprintf("line %v: %v", i.Line, s)
+	}
+	return s
+}
+
+// Issues is a list of issues, which can be used as error
+type Issues []Issue
+
+func (is Issues) Error() string {
+	s := make([]string, 0, len(is))
+	for _, i := range is {
+		s = append(s, i.String())
+	}
+	return strings.Join(s, "; ")
+}
+
+// Errors returns the issues which are not warnings
+func (is Issues) Errors() Issues {
+	var errs Issues
+	for _, i := range is {
+		if !i.Warning {
+			errs = append(errs, i)
+		}
+	}
+	return errs
+}
+
+// Warnings returns the issues which are warnings
+func (is Issues) Warnings() Issues {
+	var warnings Issues
+	for _, i := range is {
+		if i.Warning {
+			warnings = append(warnings, i)
+		}
+	}
+	return warnings
+}
+
+// Err returns the errors as error or nil, if there are none
+func (is Issues) Err() error {
+	errs := is.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validator collects the issues found in a configuration
+type validator struct {
+	// sources maps the paths of the fields to their sources
+	sources map[string]Source
+	issues  Issues
+	// programs caches the compiled expressions of the configuration, so that
+	// they are released together with it
+	programs map[string]cel.Program
+}
+
+func (v *validator) errorf(path, format string, a ...interface{}) {
+	v.add(Issue{Path: path, Message: fmt.Sprintf(format, a...)})
+}
+
+func (v *validator) warnf(path, format string, a ...interface{}) {
+	v.add(Issue{Path: path, Message: fmt.Sprintf(format, a...), Warning: true})
+}
+
+func (v *validator) add(i Issue) {
+	src := v.source(i.Path)
+	i.Source = src.Name
+	i.Line = src.Line
+	v.issues = append(v.issues, i)
+}
+
+// source returns the source of the path or of its closest parent
+func (v *validator) source(path string) Source {
+	for path != "" {
+		if src, ok := v.sources[path]; ok {
+			return src
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return Source{}
+}
+
+// Validate parses the configuration strictly and validates it. Unknown fields
+// and invalid values are reported as errors, questionable but usable settings
+// as warnings. The configuration is only usable, if there are no errors.
+func Validate(content []byte) (*Config, Issues) {
+	s := &Snapshot{layers: []layer{{content: content}}}
+	return s.Parse()
+}
+
+// validate decodes and validates the merged configuration
+func (v *validator) validate(root *yamlv3.Node) *Config {
+	cfg := &Config{}
+	if root == nil {
+		root = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	}
+	v.fields(root, reflect.TypeOf(cfg).Elem(), "")
+
+	content, err := yamlv3.Marshal(root)
+	if err != nil {
+		v.errorf("", "failed to encode config, %v", err)
+		return cfg
+	}
+	err = yaml.Unmarshal(content, cfg)
+	if err != nil {
+		v.errorf("", "failed to unmarshal config, %v", err)
+		return cfg
+	}
+
+	cfg.ReconcilationInterval = v.duration("reconcilationInterval", cfg.ReconcilationIntervalHelper)
+	if cfg.RestartIntervalHelper != "" || needsRestartInterval(cfg) {
+		cfg.RestartInterval = v.duration("restartInterval", cfg.RestartIntervalHelper)
+	}
+	if cfg.LivenessIntervals == 0 {
+		cfg.LivenessIntervals = defaultLivenessIntervals
+	}
+	v.matcher("include", &cfg.Include)
+	v.matcher("exclude", &cfg.Exclude)
+	v.overlap(cfg)
+	v.rules(cfg)
+	v.notifications(cfg.Notifications)
+	return cfg
+}
+
+// fields reports fields unknown to the type below the node
+func (v *validator) fields(node *yamlv3.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yamlv3.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			p := joinPath(path, key.Value)
+			f, ok := fieldByJSONName(t, key.Value)
+			if !ok {
+				v.errorf(p, "unknown field")
+				continue
+			}
+			v.fields(value, f.Type, p)
+		}
+	case reflect.Slice:
+		if node.Kind != yamlv3.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			v.fields(item, t.Elem(), fmt.Sprintf("%v[%v]", path, i))
+		}
+	case reflect.Map:
+		if node.Kind != yamlv3.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.fields(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	}
+}
+
+// fieldByJSONName returns the field of the struct type with the JSON name
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// duration parses a required, positive duration
+func (v *validator) duration(path, s string) time.Duration {
+	if s == "" {
+		v.errorf(path, "required")
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		v.errorf(path, "invalid duration %q", s)
+		return 0
+	}
+	if d <= 0 {
+		v.errorf(path, "must be positive, got %v", s)
+	}
+	return d
+}
+
+func (v *validator) matcher(path string, m *Matcher) {
+	if m.Enabled && len(m.Selectors) == 0 {
+		v.errorf(path+".selectors", "at least one selector is required, if enabled")
+	}
+	if !m.Enabled && len(m.Selectors) > 0 {
+		v.warnf(path+".enabled", "selectors are ignored, since the matcher is not enabled")
+	}
+	for i := range m.Selectors {
+		p := fmt.Sprintf("%v.selectors[%v]", path, i)
+		if m.Selectors[i].isEmpty() {
+			v.warnf(p, "empty selector matches all apps")
+		}
+		v.selector(p, &m.Selectors[i])
+	}
+}
+
+func (v *validator) selector(path string, s *Selector) {
+	// Patterns without wildcards have to be valid names
+	if s.Namespace != "" && !strings.ContainsAny(s.Namespace, "*?[\\") {
+		for _, msg := range validation.IsDNS1123Label(s.Namespace) {
+			v.errorf(path+".namespace", "invalid namespace %q, %v", s.Namespace, msg)
+		}
+	}
+	for i, k := range s.Kinds {
+		known := false
+		for _, kind := range Kinds {
+			known = known || strings.EqualFold(k, kind)
+		}
+		if !known {
+			v.errorf(fmt.Sprintf("%v.kinds[%v]", path, i), "unknown kind %q, must be one of %v", k, strings.Join(Kinds, ", "))
+		}
+	}
+	// Invalid labels are already reported in detail
+	n := len(v.issues)
+	keys := make([]string, 0, len(s.MatchLabels))
+	for k := range s.MatchLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := s.MatchLabels[k]
+		p := joinPath(path+".matchLabels", k)
+		for _, msg := range validation.IsQualifiedName(k) {
+			v.errorf(p, "invalid label key %q, %v", k, msg)
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			v.errorf(p, "invalid label value %q, %v", value, msg)
+		}
+	}
+	for i, o := range s.Owners {
+		err := o.validate()
+		if err != nil {
+			v.errorf(fmt.Sprintf("%v.owners[%v]", path, i), "%v", err)
+		}
+	}
+	for i, img := range s.Images {
+		err := img.validate()
+		if err != nil {
+			v.errorf(fmt.Sprintf("%v.images[%v]", path, i), "%v", err)
+		}
+	}
+	if v.programs == nil {
+		v.programs = make(map[string]cel.Program)
+	}
+	if s.Expression != "" {
+		_, err := compileExpression(s.Expression, v.programs)
+		if err != nil {
+			v.errorf(path+".expression", "%v", err)
+			return
+		}
+	}
+	compiled, err := s.compile(v.programs)
+	if err != nil {
+		if len(v.issues) == n {
+			v.errorf(path, "%v", err)
+		}
+		return
+	}
+	s.compiled = compiled
+}
+
+// overlap warns about include selectors, which only select apps also
+// excluded by an exclude selector
+func (v *validator) overlap(cfg *Config) {
+	if !cfg.Include.Enabled || !cfg.Exclude.Enabled {
+		return
+	}
+	for i, in := range cfg.Include.Selectors {
+		for j, ex := range cfg.Exclude.Selectors {
+			if covers(ex, in) {
+				v.warnf(fmt.Sprintf("include.selectors[%v]", i), "all apps selected are excluded by exclude.selectors[%v]", j)
+			}
+		}
+	}
+}
+
+// covers returns true, if every app selected by b is also selected by a. It
+// is conservative and only detects obvious cases.
+func covers(a, b Selector) bool {
+	if a.Namespace != "" {
+		if b.Namespace == "" {
+			return false
+		}
+		if a.Namespace != b.Namespace {
+			// A literal namespace matching the pattern
+			ok, _ := path.Match(a.Namespace, b.Namespace)
+			if !ok || strings.ContainsAny(b.Namespace, "*?[\\") {
+				return false
+			}
+		}
+	}
+	if a.NamespaceRegex != "" && a.NamespaceRegex != b.NamespaceRegex {
+		return false
+	}
+	if a.Name != "" && a.Name != b.Name {
+		return false
+	}
+	if a.NameRegex != "" && a.NameRegex != b.NameRegex {
+		return false
+	}
+	if len(a.Kinds) > 0 {
+		if len(b.Kinds) == 0 {
+			return false
+		}
+		for _, bk := range b.Kinds {
+			found := false
+			for _, ak := range a.Kinds {
+				found = found || strings.EqualFold(ak, bk)
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for k, value := range a.MatchLabels {
+		if bv, ok := b.MatchLabels[k]; !ok || bv != value {
+			return false
+		}
+	}
+	if len(a.Owners) > 0 && !reflect.DeepEqual(a.Owners, b.Owners) {
+		return false
+	}
+	if a.HasControllerOwner != nil && !reflect.DeepEqual(a.HasControllerOwner, b.HasControllerOwner) {
+		return false
+	}
+	if len(a.Images) > 0 && !reflect.DeepEqual(a.Images, b.Images) {
+		return false
+	}
+	if a.Expression != "" && a.Expression != b.Expression {
+		return false
+	}
+	if a.NamespaceSelector != nil && !reflect.DeepEqual(a.NamespaceSelector, b.NamespaceSelector) {
+		return false
+	}
+	for _, ae := range a.MatchExpressions {
+		found := false
+		for _, be := range b.MatchExpressions {
+			found = found || reflect.DeepEqual(ae, be)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// needsRestartInterval checks if the global restart interval is used by any
+// rule
+func needsRestartInterval(cfg *Config) bool {
+	if len(cfg.Rules) == 0 {
+		return true
+	}
+	for _, r := range cfg.Rules {
+		if r.Action != ActionIgnore && r.RestartIntervalHelper == "" && r.Schedule == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) rules(cfg *Config) {
+	if len(cfg.Rules) == 0 {
+		return
+	}
+	if cfg.Include.Enabled || cfg.Exclude.Enabled {
+		v.errorf("rules", "include and exclude can not be enabled together with rules")
+	}
+	for i := range cfg.Rules {
+		path := fmt.Sprintf("rules[%v]", i)
+		r := &cfg.Rules[i]
+		switch r.Action {
+		case "", ActionRestart:
+			if r.RestartIntervalHelper != "" {
+				r.RestartInterval = v.duration(path+".restartInterval", r.RestartIntervalHelper)
+			}
+			if r.Schedule != nil {
+				v.schedule(path, r)
+			}
+			if r.Window != nil {
+				compiled, err := r.Window.compile()
+				if err != nil {
+					v.errorf(path+".window", "%v", err)
+				}
+				r.Window.compiled = compiled
+			}
+			if r.Strategy != nil {
+				v.strategy(path+".strategy", r.Strategy)
+			}
+		case ActionIgnore:
+			if r.RestartIntervalHelper != "" || r.Schedule != nil || r.Window != nil || r.Strategy != nil {
+				v.warnf(path, "restartInterval, schedule, window and strategy are ignored with action %v", ActionIgnore)
+			}
+		default:
+			v.errorf(path+".action", "unknown action %q, must be %v or %v", r.Action, ActionRestart, ActionIgnore)
+		}
+		if r.Selector != nil {
+			v.selector(path+".selector", r.Selector)
+		}
+
+		// Rules never reached, since an earlier rule matches all their apps
+		for j := 0; j < i; j++ {
+			earlier := cfg.Rules[j]
+			if earlier.Selector == nil || earlier.Selector.isEmpty() ||
+				r.Selector != nil && covers(*earlier.Selector, *r.Selector) {
+				v.warnf(path, "never matches, since all apps are matched by rules[%v] before", j)
+				break
+			}
+		}
+	}
+}
+
+// schedule compiles the schedule of a rule, which replaces its interval
+func (v *validator) schedule(path string, r *Rule) {
+	if r.RestartIntervalHelper != "" {
+		v.errorf(path+".schedule", "can not be set together with restartInterval")
+	}
+	compiled, err := r.Schedule.compile()
+	if err != nil {
+		v.errorf(path+".schedule", "%v", err)
+		return
+	}
+	r.Schedule.compiled = compiled
+	if r.Schedule.Next(time.Now()).IsZero() {
+		v.errorf(path+".schedule.cron", "%q never matches", r.Schedule.Cron)
+	}
+}
+
+func (v *validator) strategy(path string, s *Strategy) {
+	if s.MaxConcurrent < 0 {
+		v.errorf(path+".maxConcurrent", "must not be negative, got %v", s.MaxConcurrent)
+	}
+	if s.MaxRestarts < 0 {
+		v.errorf(path+".maxRestarts", "must not be negative, got %v", s.MaxRestarts)
+	}
+	if s.MaxRestarts > 0 || s.BudgetPeriodHelper != "" {
+		s.BudgetPeriod = v.duration(path+".budgetPeriod", s.BudgetPeriodHelper)
+	}
+}
+
+func (v *validator) notifications(n Notifications) {
+	for i, w := range n.Webhooks {
+		path := fmt.Sprintf("notifications.webhooks[%v]", i)
+		v.url(path+".url", w.URL)
+		v.events(path+".events", w.Events)
+		v.maxRetries(path+".maxRetries", w.MaxRetries)
+		if w.Template != "" {
+			_, err := template.New("").Parse(w.Template)
+			if err != nil {
+				v.errorf(path+".template", "invalid template, %v", err)
+			}
+		}
+	}
+	for i, c := range n.CloudEvents {
+		path := fmt.Sprintf("notifications.cloudEvents[%v]", i)
+		v.url(path+".sink", c.Sink)
+		v.events(path+".events", c.Events)
+		v.maxRetries(path+".maxRetries", c.MaxRetries)
+	}
+}
+
+func (v *validator) url(path, s string) {
+	if s == "" {
+		v.errorf(path, "required")
+		return
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.errorf(path, "invalid HTTP URL %q", s)
+	}
+}
+
+func (v *validator) events(path string, events []string) {
+	for i, e := range events {
+		known := false
+		for _, k := range notificationEvents {
+			known = known || e == k
+		}
+		if !known {
+			v.errorf(fmt.Sprintf("%v[%v]", path, i), "unknown event %q, must be one of %v", e, strings.Join(notificationEvents, ", "))
+		}
+	}
+}
+
+func (v *validator) maxRetries(path string, n *int) {
+	if n != nil && *n < 0 {
+		v.errorf(path, "must not be negative")
+	}
+}
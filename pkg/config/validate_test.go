@@ -0,0 +1,202 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		errors   []string
+		warnings []string
+	}{
+		{
+			name:    "Valid",
+			content: "reconcilationInterval: 1m\nrestartInterval: 24h\n",
+		},
+		{
+			name:    "Unknown field",
+			content: "reconciliationInterval: 1m\nrestartInterval: 24h\n",
+			errors: []string{
+				"line 1: reconciliationInterval: unknown field",
+				"reconcilationInterval: required",
+			},
+		},
+		{
+			name:    "Non-positive duration",
+			content: "reconcilationInterval: 1m\nrestartInterval: 0s\n",
+			errors:  []string{"line 2: restartInterval: must be positive, got 0s"},
+		},
+		{
+			name: "Invalid labels",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+include:
+  enabled: true
+  selectors:
+    - matchLabels:
+        app: "-web"
+`,
+			errors: []string{`line 7: include.selectors[0].matchLabels.app: invalid label value "-web"`},
+		},
+		{
+			name: "Enabled without selectors",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+exclude:
+  enabled: true
+`,
+			errors: []string{"line 3: exclude.selectors: at least one selector is required, if enabled"},
+		},
+		{
+			name: "Overlap",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+include:
+  enabled: true
+  selectors:
+    - namespace: web
+      matchLabels:
+        app: shop
+exclude:
+  enabled: true
+  selectors:
+    - namespace: web
+`,
+			warnings: []string{"line 6: include.selectors[0]: all apps selected are excluded by exclude.selectors[0]"},
+		},
+		{
+			name: "Schedule",
+			content: `reconcilationInterval: 1m
+rules:
+  - schedule:
+      cron: "0 3 * * 0"
+      timeZone: Europe/Berlin
+`,
+		},
+		{
+			name: "Schedule with interval",
+			content: `reconcilationInterval: 1m
+rules:
+  - restartInterval: 24h
+    schedule:
+      cron: "0 3 * * 0"
+`,
+			errors: []string{"line 4: rules[0].schedule: can not be set together with restartInterval"},
+		},
+		{
+			name: "Invalid schedule",
+			content: `reconcilationInterval: 1m
+rules:
+  - schedule:
+      cron: "0 25 * * *"
+`,
+			errors: []string{`line 3: rules[0].schedule: invalid hour "25"`},
+		},
+		{
+			name: "Schedule never matches",
+			content: `reconcilationInterval: 1m
+rules:
+  - schedule:
+      cron: "0 0 31 4 *"
+`,
+			errors: []string{`line 4: rules[0].schedule.cron: "0 0 31 4 *" never matches`},
+		},
+		{
+			name: "Strategy",
+			content: `reconcilationInterval: 1m
+restartInterval: 24h
+rules:
+  - strategy:
+      maxConcurrent: -1
+      maxRestarts: 3
+`,
+			errors: []string{
+				"line 5: rules[0].strategy.maxConcurrent: must not be negative, got -1",
+				"line 4: rules[0].strategy.budgetPeriod: required",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, issues := Validate([]byte(tt.content))
+			check := func(kind string, got Issues, want []string) {
+				if len(got) != len(want) {
+					t.Fatalf("%v = %v, want %v", kind, got, want)
+				}
+				for i := range want {
+					if !strings.HasPrefix(got[i].String(), want[i]) {
+						t.Errorf("%v[%v] = %v, want %v", kind, i, got[i], want[i])
+					}
+				}
+			}
+			check("errors", issues.Errors(), tt.errors)
+			check("warnings", issues.Warnings(), tt.warnings)
+		})
+	}
+}
+
+// TestSchema checks that the published JSON Schema knows the same fields as
+// the configuration
+func TestSchema(t *testing.T) {
+	content, err := ioutil.ReadFile("config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	err = json.Unmarshal(content, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	definitions := schema["definitions"].(map[string]interface{})
+
+	// resolve follows references and arrays to the object schema
+	var resolve func(s map[string]interface{}) map[string]interface{}
+	resolve = func(s map[string]interface{}) map[string]interface{} {
+		if ref, ok := s["$ref"].(string); ok {
+			return resolve(definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{}))
+		}
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			return resolve(items)
+		}
+		return s
+	}
+
+	var compare func(path string, s map[string]interface{}, typ reflect.Type)
+	compare = func(path string, s map[string]interface{}, typ reflect.Type) {
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		var want, got []string
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" || f.PkgPath != "" {
+				continue
+			}
+			want = append(want, name)
+			if p, ok := properties[name].(map[string]interface{}); ok {
+				compare(joinPath(path, name), resolve(p), f.Type)
+			}
+		}
+		for name := range properties {
+			got = append(got, name)
+		}
+		sort.Strings(want)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("schema properties of %q = %v, want %v", path, got, want)
+		}
+	}
+	compare("", schema, reflect.TypeOf(Config{}))
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	opsReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_restarter_config_reloads_total",
+		Help: "The total number of configuration reloads by result",
+	}, []string{"result"})
+	opsLastReloadSuccessful = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "k8s_restarter_config_last_reload_successful",
+		Help: "Whether the last configuration reload was successful",
+	})
+)
+
+// Watcher watches the configuration files for changes. The files are polled,
+// so that also the symlink swaps used by Kubernetes to update mounted
+// ConfigMaps are detected.
+type Watcher struct {
+	loader   *Loader
+	interval time.Duration
+	hash     [sha256.Size]byte
+	reload   chan struct{}
+}
+
+// NewWatcher returns a new watcher polling the files of the loader in the
+// interval. The snapshot is the snapshot of the currently used configuration.
+func NewWatcher(loader *Loader, interval time.Duration, snapshot *Snapshot) *Watcher {
+	opsLastReloadSuccessful.Set(1)
+	return &Watcher{
+		loader:   loader,
+		interval: interval,
+		hash:     snapshot.Hash(),
+		reload:   make(chan struct{}, 1),
+	}
+}
+
+// Reload triggers an immediate check of the files, e.g. on SIGHUP
+func (w *Watcher) Reload() {
+	select {
+	case w.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Run watches the files until the context is done. The apply function is
+// called with every new configuration. If the new configuration can not be
+// read or is rejected by apply, the reject function is called with the error
+// and the last good configuration is kept. Configurations rejected by apply
+// are retried in the next interval.
+func (w *Watcher) Run(ctx context.Context, apply func(*Config) error, reject func(error)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.reload:
+		case <-w.loader.changed():
+		}
+		changed, err := w.check(apply)
+		if err != nil {
+			opsReloads.WithLabelValues("failed").Inc()
+			opsLastReloadSuccessful.Set(0)
+			reject(err)
+			continue
+		}
+		if changed {
+			opsReloads.WithLabelValues("success").Inc()
+			opsLastReloadSuccessful.Set(1)
+		}
+	}
+}
+
+// check reads the files and applies the configuration, if it changed
+func (w *Watcher) check(apply func(*Config) error) (bool, error) {
+	snapshot, err := w.loader.Snapshot()
+	if err != nil {
+		return false, err
+	}
+	hash := snapshot.Hash()
+	if hash == w.hash {
+		return false, nil
+	}
+	cfg, issues := snapshot.Parse()
+	err = issues.Err()
+	if err != nil {
+		// Remember the hash of invalid configurations, so that they are
+		// only rejected once
+		w.hash = hash
+		return false, fmt.Errorf("invalid config, %w", err)
+	}
+	// Configurations rejected by apply are retried, since they may depend on
+	// files outside of the snapshot, e.g. secret files mounted later
+	err = apply(cfg)
+	if err != nil {
+		return false, fmt.Errorf("failed to apply config, %w", err)
+	}
+	w.hash = hash
+	return true, nil
+}
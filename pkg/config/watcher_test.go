@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigMap writes the content like the kubelet updates mounted
+// ConfigMaps, i.e. by swapping the ..data symlink to a new directory
+func writeConfigMap(t *testing.T, dir, version, content string) {
+	t.Helper()
+	versionDir := filepath.Join(dir, version)
+	err := os.Mkdir(versionDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(versionDir, "config.yaml"), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	err = os.Symlink(version, tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(tmp, filepath.Join(dir, "..data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigMap(t, dir, "v1", "reconcilationInterval: 1m\nrestartInterval: 10m")
+	path := filepath.Join(dir, "config.yaml")
+	err := os.Symlink(filepath.Join("..data", "config.yaml"), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := make(chan *Config, 10)
+	rejected := make(chan error, 10)
+	loader := &Loader{File: path, Environ: []string{}}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(loader, time.Hour, snapshot)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, func(cfg *Config) error {
+		applied <- cfg
+		return nil
+	}, func(err error) {
+		rejected <- err
+	})
+
+	// Valid change
+	writeConfigMap(t, dir, "v2", "reconcilationInterval: 1m\nrestartInterval: 20m")
+	w.Reload()
+	select {
+	case cfg := <-applied:
+		if cfg.RestartInterval != 20*time.Minute {
+			t.Errorf("RestartInterval = %v, want %v", cfg.RestartInterval, 20*time.Minute)
+		}
+	case err := <-rejected:
+		t.Fatalf("unexpected rejection, %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not applied")
+	}
+
+	// Invalid change
+	writeConfigMap(t, dir, "v3", "restartInterval: twenty minutes")
+	w.Reload()
+	select {
+	case cfg := <-applied:
+		t.Fatalf("unexpected config applied, %v", cfg)
+	case <-rejected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not rejected")
+	}
+}
+
+func TestWatcher_RetryApply(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	err := ioutil.WriteFile(path, []byte("reconcilationInterval: 1m\nrestartInterval: 10m"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := &Loader{File: path, Environ: []string{}}
+	snapshot, err := loader.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(loader, time.Hour, snapshot)
+
+	err = ioutil.WriteFile(path, []byte("reconcilationInterval: 1m\nrestartInterval: 20m"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The secret file is missing
+	failing := func(*Config) error { return fmt.Errorf("missing secret file") }
+	_, err = w.check(failing)
+	if err == nil || !strings.Contains(err.Error(), "failed to apply") {
+		t.Fatalf("check() error = %v, want failed apply", err)
+	}
+	// The same snapshot is retried, once the secret file is mounted
+	var applied *Config
+	changed, err := w.check(func(cfg *Config) error {
+		applied = cfg
+		return nil
+	})
+	if err != nil || !changed || applied == nil || applied.RestartInterval != 20*time.Minute {
+		t.Fatalf("check() = %v, %v, applied %v", changed, err, applied)
+	}
+	changed, err = w.check(failing)
+	if err != nil || changed {
+		t.Errorf("check() = %v, %v, want unchanged", changed, err)
+	}
+}
@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/audit"
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/notify"
+	"github.com/shaardie/k8s-restarter/pkg/server"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Restart restarts a single app ahead of schedule, if it passes the checks of
+// checkRestart
+func (c *Controller) Restart(ctx context.Context, namespace, kind, name string) error {
+	app, err := c.getApp(ctx, namespace, kind, name)
+	if err != nil {
+		return err
+	}
+	err = c.restartChecked(ctx, app, triggerAPI, false)
+	if err != nil {
+		return err
+	}
+	c.appLogger(app).Sugar().Infow("restarted on request")
+	return nil
+}
+
+// Postpone postpones the next restart of a single app until the given time
+func (c *Controller) Postpone(ctx context.Context, namespace, kind, name string, until time.Time) error {
+	app, err := c.getApp(ctx, namespace, kind, name)
+	if err != nil {
+		return err
+	}
+	logger := c.appLogger(app)
+
+	state, err := c.evaluate(app, time.Now())
+	if err != nil {
+		return err
+	}
+	if !state.Selected {
+		return fmt.Errorf("%w, %v %v/%v is excluded", server.ErrRejected, kind, namespace, name)
+	}
+
+	patch, err := postponePatch(until)
+	if err != nil {
+		return err
+	}
+	err = observeAPI(resourceName(app), "patch", func() error {
+		return app.Patch(ctx, c.Clientset, patch)
+	})
+	c.audit(ctx, app, audit.DecisionPostpone, triggerAPI, "postponed until "+until.Format(time.RFC3339), patch, err)
+	if err != nil {
+		return fmt.Errorf("failed to set postponement on %v %v/%v, %w", kind, namespace, name, err)
+	}
+	logger.Sugar().Infow("postponed on request", "until", until)
+	c.Notifier.Notify(notify.Event{
+		Type:      notify.EventPostponed,
+		Namespace: app.GetNamespace(),
+		Kind:      app.GetKind(),
+		Name:      app.GetName(),
+		Trigger:   triggerAPI,
+		Reason:    "postponed until " + until.Format(time.RFC3339),
+	})
+	return nil
+}
+
+// RestartImage restarts all apps running an image matching the pattern, e.g.
+// docker.io/library/alpine:3.*. Apps failing the checks of Restart are
+// skipped.
+func (c *Controller) RestartImage(ctx context.Context, image string) ([]server.ImageRestart, error) {
+	selector, err := config.ParseImageSelector(image)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %v", server.ErrRejected, err)
+	}
+	apps, err := c.listApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []server.ImageRestart{}
+	for _, app := range apps {
+		ref, ok := selector.MatchesAny(podImages(app.GetPodTemplateSpec()))
+		if !ok {
+			continue
+		}
+		result := server.ImageRestart{
+			Namespace: app.GetNamespace(),
+			Kind:      app.GetKind(),
+			Name:      app.GetName(),
+			Image:     ref,
+		}
+		err := c.restartChecked(ctx, app, triggerAPI, false)
+		switch {
+		case err == nil:
+			result.Result = "restarted"
+		case errors.Is(err, server.ErrRejected):
+			result.Result = "skipped"
+			result.Error = err.Error()
+		default:
+			result.Result = "failed"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	c.Logger.Sugar().Infow("restarted apps running image on request", "image", image, "apps", len(results))
+	return results, nil
+}
+
+// restartChecked restarts an app ahead of schedule, if it passes the checks
+// of checkRestart. Apps, which can not be evaluated, are never restarted.
+func (c *Controller) restartChecked(ctx context.Context, app App, trigger string, force bool) error {
+	now := time.Now()
+	state, err := c.evaluate(app, now)
+	if err != nil {
+		return err
+	}
+	err = c.checkRestart(ctx, app, state, now, force)
+	if err != nil {
+		return err
+	}
+	reason := "requested"
+	if force {
+		reason = "forced"
+	}
+	return c.restart(ctx, app, &state, trigger, reason)
+}
+
+// checkRestart runs the safety checks of the reconcilation on an app
+// restarted on request, ignoring its age and any postponement. The restart
+// is rejected, if the app is excluded, while restarts are paused, if the app
+// is not ready, outside the window of its rule or if a PodDisruptionBudget of
+// its Pods allows no disruption. Forced restarts only skip the last three
+// checks.
+func (c *Controller) checkRestart(ctx context.Context, app App, state server.App, now time.Time, force bool) error {
+	if state.Status == statusExcluded {
+		return fmt.Errorf("%w, %v %v/%v is excluded", server.ErrRejected, app.GetKind(), app.GetNamespace(), app.GetName())
+	}
+	paused, err := c.IsPaused(ctx)
+	if err != nil {
+		return err
+	}
+	if paused {
+		return fmt.Errorf("%w, restarts are paused", server.ErrRejected)
+	}
+	if force {
+		return nil
+	}
+	if state.Status == statusNotReady {
+		return fmt.Errorf("%w, %v %v/%v is not ready", server.ErrRejected, app.GetKind(), app.GetNamespace(), app.GetName())
+	}
+	rule, ok, err := c.config().Match(c.target(app))
+	if err != nil {
+		return err
+	}
+	if ok && rule.Window != nil && !rule.Window.Contains(now) {
+		return fmt.Errorf("%w, %v %v/%v is outside the window of rule %v", server.ErrRejected, app.GetKind(), app.GetNamespace(), app.GetName(), rule.Name)
+	}
+	return c.checkDisruptionBudgets(ctx, app)
+}
+
+// RestartManually restarts a single app on request of an operator, e.g. by
+// the restart command, and returns the restarted app. It runs the same checks
+// as a restart via the API, forced restarts skip the status, window and
+// disruption budget checks.
+func (c *Controller) RestartManually(ctx context.Context, namespace, kind, name string, force bool) (App, error) {
+	app, err := c.getApp(ctx, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	err = c.restartChecked(ctx, app, triggerCLI, force)
+	if err != nil {
+		return nil, err
+	}
+	c.appLogger(app).Sugar().Infow("restarted manually", "forced", force)
+	return app, nil
+}
+
+// appKinds maps the kinds of the apps, their plurals and short names to the
+// kinds
+var appKinds = map[string]string{
+	"deployment":   "Deployment",
+	"deployments":  "Deployment",
+	"deploy":       "Deployment",
+	"statefulset":  "StatefulSet",
+	"statefulsets": "StatefulSet",
+	"sts":          "StatefulSet",
+	"daemonset":    "DaemonSet",
+	"daemonsets":   "DaemonSet",
+	"ds":           "DaemonSet",
+}
+
+// ParseAppRef parses a reference to an app like kubectl, e.g. deploy/web or
+// statefulset.apps/db
+func ParseAppRef(ref string) (kind, name string, err error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid app %q, expected kind/name", ref)
+	}
+	kind, ok := appKinds[strings.TrimSuffix(strings.ToLower(parts[0]), ".apps")]
+	if !ok {
+		return "", "", fmt.Errorf("unknown kind %v, expected deployment, statefulset or daemonset", parts[0])
+	}
+	return kind, parts[1], nil
+}
+
+// getApp gets a single app from the Kubernetes API. The kind is matched case
+// insensitive.
+func (c *Controller) getApp(ctx context.Context, namespace, kind, name string) (App, error) {
+	var app App
+	var err error
+	switch strings.ToLower(kind) {
+	case "deployment":
+		err = observeAPI("deployments", "get", func() error {
+			d, err := c.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+			app = (*Deployment)(d)
+			return err
+		})
+	case "statefulset":
+		err = observeAPI("statefulsets", "get", func() error {
+			s, err := c.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			app = (*StatefulSet)(s)
+			return err
+		})
+	case "daemonset":
+		err = observeAPI("daemonsets", "get", func() error {
+			d, err := c.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			app = (*DaemonSet)(d)
+			return err
+		})
+	default:
+		return nil, fmt.Errorf("%w, unknown kind %v", server.ErrRejected, kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %v %v/%v, %w", kind, namespace, name, err)
+	}
+	return app, nil
+}
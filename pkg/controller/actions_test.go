@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/server"
+	"go.uber.org/zap"
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseAppRef(t *testing.T) {
+	tests := []struct {
+		ref      string
+		wantKind string
+		wantErr  bool
+	}{
+		{"deploy/web", "Deployment", false},
+		{"Deployment/web", "Deployment", false},
+		{"statefulset.apps/web", "StatefulSet", false},
+		{"ds/web", "DaemonSet", false},
+		{"pod/web", "", true},
+		{"web", "", true},
+		{"deploy/", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			kind, name, err := ParseAppRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAppRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (kind != tt.wantKind || name != "web") {
+				t.Errorf("ParseAppRef() = %v, %v", kind, name)
+			}
+		})
+	}
+}
+
+func Test_blockingDisruptionBudget(t *testing.T) {
+	app := &Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+	app.Spec.Template.Labels = map[string]string{"app": "web"}
+	pdb := func(app string, allowed int32) policyv1.PodDisruptionBudget {
+		return policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: app},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+		}
+	}
+	tests := []struct {
+		name    string
+		pdbs    []policyv1.PodDisruptionBudget
+		wantErr bool
+	}{
+		{"no budgets", nil, false},
+		{"disruption allowed", []policyv1.PodDisruptionBudget{pdb("web", 1)}, false},
+		{"no disruption allowed", []policyv1.PodDisruptionBudget{pdb("web", 0)}, true},
+		{"other app", []policyv1.PodDisruptionBudget{pdb("db", 0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := blockingDisruptionBudget(tt.pdbs, app)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("blockingDisruptionBudget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkRestart(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	app := func(namespace string, ready bool, postponed bool) App {
+		d := &Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              "web",
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Minute)),
+		}}
+		d.Spec.Template.Labels = map[string]string{"app": "web"}
+		d.Status.Replicas = 1
+		if ready {
+			d.Status.UpdatedReplicas = 1
+		}
+		if postponed {
+			d.Annotations = map[string]string{postponedUntilAnnotation: now.Add(time.Hour).Format(time.RFC3339)}
+		}
+		return d
+	}
+	pause := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8s-restarter", Name: "pause"},
+		Data:       map[string]string{pausedKey: "true"},
+	}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+	cfg := &config.Config{
+		RestartInterval: time.Hour,
+		Rules: []config.Rule{
+			{Selector: &config.Selector{Namespace: "kube-system"}, Action: config.ActionIgnore},
+			{Selector: &config.Selector{Namespace: "nightly"}, Window: &config.Window{Start: "01:00", End: "04:00"}},
+			{},
+		},
+	}
+	tests := []struct {
+		name    string
+		app     App
+		objects []runtime.Object
+		wantErr bool
+	}{
+		{"ready", app("default", true, false), nil, false},
+		{"postponed", app("default", true, true), nil, false},
+		{"excluded", app("kube-system", true, false), nil, true},
+		{"not ready", app("default", false, false), nil, true},
+		{"postponed and not ready", app("default", false, true), nil, true},
+		{"outside window", app("nightly", true, false), nil, true},
+		{"paused", app("default", true, false), []runtime.Object{pause}, true},
+		{"disruption budget", app("default", true, false), []runtime.Object{pdb}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{
+				Cfg:            cfg,
+				Clientset:      fake.NewSimpleClientset(tt.objects...),
+				PauseNamespace: "k8s-restarter",
+				PauseConfigMap: "pause",
+			}
+			state, err := c.evaluate(tt.app, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.checkRestart(context.Background(), tt.app, state, now, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkRestart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, server.ErrRejected) {
+				t.Errorf("checkRestart() error = %v, want rejection", err)
+			}
+		})
+	}
+}
+
+func TestController_RestartManually(t *testing.T) {
+	window := &config.Window{Start: "00:00", End: "00:00"}
+	staging := &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "staging"}}
+	tests := []struct {
+		name        string
+		rules       []config.Rule
+		paused      bool
+		force       bool
+		wantErr     bool
+		wantRestart bool
+	}{
+		{"ready", []config.Rule{{}}, false, false, false, true},
+		{"outside window", []config.Rule{{Window: window}}, false, false, true, false},
+		{"forced outside window", []config.Rule{{Window: window}}, false, true, false, true},
+		{"forced excluded", []config.Rule{{Action: config.ActionIgnore}}, false, true, true, false},
+		{"forced paused", []config.Rule{{}}, true, true, true, false},
+		{"forced unknown namespace", []config.Rule{{Selector: &config.Selector{NamespaceSelector: staging}, Action: config.ActionIgnore}, {}}, false, true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              "web",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute)),
+			}}
+			d.Status.Replicas = 1
+			d.Status.UpdatedReplicas = 1
+			clientset := fake.NewSimpleClientset(d, &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "k8s-restarter", Name: "pause"},
+				Data:       map[string]string{pausedKey: strconv.FormatBool(tt.paused)},
+			})
+			c := &Controller{
+				Cfg:            &config.Config{RestartInterval: time.Hour, Rules: tt.rules},
+				Clientset:      clientset,
+				Logger:         zap.NewNop(),
+				PauseNamespace: "k8s-restarter",
+				PauseConfigMap: "pause",
+			}
+			_, err := c.RestartManually(context.Background(), "default", "Deployment", "web", tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RestartManually() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, restarted := got.Spec.Template.Annotations[restartedAtAnnotation]
+			if restarted != tt.wantRestart {
+				t.Errorf("RestartManually() restarted = %v, want %v", restarted, tt.wantRestart)
+			}
+		})
+	}
+}
@@ -23,6 +23,11 @@ type App interface {
 	// e.g. if a Deployment has a proper Number of Pods.
 	StatusOK() bool
 
-	// Update updates the app with the the new set values
-	Update(context.Context, *kubernetes.Clientset) error
+	// RolloutComplete indicates, if the latest PodTemplateSpec is rolled out
+	// to all Pods, e.g. after a restart
+	RolloutComplete() bool
+
+	// Patch applies a JSON merge patch to the app and updates the app with
+	// the result
+	Patch(ctx context.Context, clientset kubernetes.Interface, patch []byte) error
 }
@@ -2,38 +2,104 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/shaardie/k8s-restarter/pkg/audit"
 	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/notify"
 	"github.com/shaardie/k8s-restarter/pkg/server"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	appv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 )
 
 const (
-	restartedAtAnnotation = "k8s-restarter.kubernetes.io/restartedAt"
-	minInterval           = 50 * time.Microsecond
+	restartedAtAnnotation    = "k8s-restarter.kubernetes.io/restartedAt"
+	postponedUntilAnnotation = "k8s-restarter.kubernetes.io/postponedUntil"
+	minInterval              = 50 * time.Microsecond
+)
+
+// Triggers of a restart
+const (
+	triggerReconcile = "reconcile"
+	triggerAPI       = "api"
+	triggerCLI       = "cli"
+)
+
+// Status of an app after the reconcilation
+const (
+	statusExcluded       = "excluded"
+	statusPostponed      = "postponed"
+	statusNotReady       = "not ready"
+	statusScheduled      = "scheduled"
+	statusDue            = "due"
+	statusDeferredPaused = "deferred: paused"
+	statusOutsideWindow  = "deferred: outside window"
+	statusRestarted      = "restarted"
+	statusFailed         = "failed"
+	statusRolloutFailed  = "rollout failed"
+
+	// statusDeferredConcurrency and statusDeferredBudget are set, if the
+	// strategy of the rule allows no further restart
+	statusDeferredConcurrency = "deferred: max concurrent"
+	statusDeferredBudget      = "deferred: budget exhausted"
 )
 
 // Controller is responsible for the reconcilation
 type Controller struct {
-	Logger    *zap.Logger
+	Logger *zap.Logger
+	// Cfg is the initial configuration. Use SetConfig to change it while
+	// running.
 	Cfg       *config.Config
-	Clientset *kubernetes.Clientset
+	Clientset kubernetes.Interface
+	// Namespace restricts the apps to a single namespace, all if empty
+	Namespace string
 	Server    *server.Server
-	stop      chan struct{}
-	done      chan struct{}
+	// PauseNamespace and PauseConfigMap reference the ConfigMap holding the
+	// pause state. If PauseConfigMap is empty, the controller can not be
+	// paused.
+	PauseNamespace string
+	PauseConfigMap string
+	// Notifier is notified about the restart lifecycle events
+	Notifier *notify.Notifier
+	// Audit records all mutating actions
+	Audit *audit.Log
+	stop  chan struct{}
+	done  chan struct{}
+	// overdue holds the next restart of overdue apps already notified
+	overdue map[[3]string]time.Time
+	// pending is the configuration swapped in before the next reconcilation
+	// together with its notification targets
+	pending        *config.Config
+	pendingTargets *notify.Targets
+	// namespaces caches the Namespace objects for the namespace selectors
+	namespaces corelisters.NamespaceLister
+	m          sync.Mutex
 }
 
 // reconcilationInfo holds information about a reconsilation loop
 type reconcilationInfo struct {
-	Excluded  int `json:"excluded"`
-	Skipped   int `json:"skipped"`
-	Restarted int `json:"restarted"`
+	Paused    bool `json:"paused"`
+	Excluded  int  `json:"excluded"`
+	Skipped   int  `json:"skipped"`
+	Restarted int  `json:"restarted"`
+	Failed    int  `json:"failed"`
+}
+
+// reconcilation is the result of a reconcilation loop
+type reconcilation struct {
+	info reconcilationInfo
+	// apps and their states at the same index
+	apps   []App
+	states []server.App
 }
 
 func (c *Controller) Stop() {
@@ -48,6 +114,11 @@ func (c *Controller) Stop() {
 func (c *Controller) Run(ctx context.Context) {
 	c.stop = make(chan struct{})
 	c.done = make(chan struct{})
+	c.heartbeat()
+	err := c.startNamespaceInformer(ctx)
+	if err != nil {
+		c.Logger.Sugar().Errorw("Failed to sync namespace cache, apps in namespaces with unknown labels are skipped by namespace selectors", "error", err)
+	}
 	var interval time.Duration
 	shoudRun := func() bool {
 		select {
@@ -64,149 +135,488 @@ func (c *Controller) Run(ctx context.Context) {
 			interval -= minInterval
 			continue
 		}
-		err := c.reconcile(ctx)
+		c.applyConfig()
+		_, err := c.reconcile(ctx)
 		if err == nil {
 			c.Server.SetHealth("controller", true)
+			c.heartbeat()
 		} else {
 			c.Logger.Sugar().Errorw("Failed to reconcile", "error", err)
 			c.Server.SetHealth("controller", false)
 		}
-		interval = c.Cfg.ReconcilationInterval
+		interval = c.config().ReconcilationInterval
 	}
 }
 
-// reconcile runs the reconcilation loop on all apps/*
-func (c *Controller) reconcile(ctx context.Context) error {
-	deployments, err := c.Clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+// SetConfig sets a new configuration, which is swapped in together with its
+// notification targets before the next reconcilation. It returns an error, if
+// the notification targets can not be created, e.g. since a secret file is
+// missing.
+func (c *Controller) SetConfig(cfg *config.Config) error {
+	targets, err := notify.Prepare(cfg.Notifications)
 	if err != nil {
-		return fmt.Errorf("failed to get deployments, %w", err)
+		return fmt.Errorf("failed to create notification targets, %w", err)
 	}
-	statefulsets, err := c.Clientset.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get statefulsets, %w", err)
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.pending = cfg
+	c.pendingTargets = targets
+	return nil
+}
+
+// applyConfig swaps in the pending configuration and its notification
+// targets, if any
+func (c *Controller) applyConfig() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.pending == nil {
+		return
 	}
-	daemonsets, err := c.Clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	err := c.Notifier.Use(c.pendingTargets)
 	if err != nil {
-		return fmt.Errorf("failed to get daemonsets, %w", err)
+		c.Logger.Sugar().Errorw("Failed to update notification targets", "error", err)
 	}
+	c.Cfg = c.pending
+	c.pending = nil
+	c.pendingTargets = nil
+	if c.Server != nil {
+		c.Server.SetConfig(c.Cfg)
+	}
+	c.Logger.Info("Applied new configuration")
+}
 
-	apps := make([]App, 0, len(deployments.Items)+len(statefulsets.Items)+len(daemonsets.Items))
-	for i := range deployments.Items {
-		apps = append(apps, (*Deployment)(&deployments.Items[i]))
+// config returns the current configuration
+func (c *Controller) config() *config.Config {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.Cfg
+}
+
+// heartbeat signals the server, that the controller is not stale. The
+// controller is considered stale, if there was no successful reconcilation
+// for the configured number of reconcilation intervals.
+func (c *Controller) heartbeat() {
+	cfg := c.config()
+	c.Server.Heartbeat("controller", time.Duration(cfg.LivenessIntervals)*cfg.ReconcilationInterval)
+}
+
+// reconcile runs the reconcilation loop on all apps/*
+func (c *Controller) reconcile(ctx context.Context) (result reconcilation, err error) {
+	ctx, span := tracer.Start(ctx, "reconcile")
+	start := time.Now()
+	defer func() {
+		opsReconcileDuration.Observe(time.Since(start).Seconds())
+		endSpan(span, err)
+	}()
+
+	apps, err := c.listApps(ctx)
+	if err != nil {
+		return result, err
 	}
-	for i := range statefulsets.Items {
-		apps = append(apps, (*StatefulSet)(&statefulsets.Items[i]))
+
+	paused, err := c.IsPaused(ctx)
+	if err != nil {
+		return result, err
 	}
-	for i := range daemonsets.Items {
-		apps = append(apps, (*DaemonSet)(&daemonsets.Items[i]))
+	if c.Server != nil {
+		c.Server.SetPaused(paused)
 	}
 
-	info := reconcilationInfo{}
+	info := reconcilationInfo{Paused: paused}
+	sortByLastRestart(apps)
+	limits := c.newRuleLimits(apps, time.Now())
+	states := make([]server.App, 0, len(apps))
 	for _, a := range apps {
-		err := c.reconcileApp(ctx, a, &info)
+		state, err := c.reconcileApp(ctx, a, &info, limits)
 		if err != nil {
-			fmt.Printf("Failed to reconcile %v/%v, %v", a.GetNamespace(), a.GetName(), err)
+			c.appLogger(a).Sugar().Errorw("Failed to reconcile", "error", err)
+			state.Status = statusFailed
+			state.Error = err.Error()
+			info.Failed++
+			opsAppFailures.WithLabelValues(a.GetNamespace(), a.GetKind(), a.GetName()).Inc()
 		}
+		states = append(states, state)
+	}
+	if c.Server != nil {
+		c.Server.SetApps(states)
 	}
+	span.SetAttributes(
+		attribute.Bool("paused", info.Paused),
+		attribute.Int("apps.excluded", info.Excluded),
+		attribute.Int("apps.skipped", info.Skipped),
+		attribute.Int("apps.restarted", info.Restarted),
+		attribute.Int("apps.failed", info.Failed),
+	)
 	opsExcluded.Set(float64(info.Excluded))
 	opsRestarts.Set(float64(info.Restarted))
 	opsSkips.Set(float64(info.Skipped))
-	opsExcludedHisto.Observe(float64(info.Excluded))
-	opsRestartsHisto.Observe(float64(info.Restarted))
-	opsSkipsHisto.Observe(float64(info.Skipped))
+	opsFailures.Set(float64(info.Failed))
+	opsLastSuccessfulReconcile.SetToCurrentTime()
+	updateAppMetrics(states)
 	c.Logger.Sugar().Infow("Reconciled", "info", info)
-	return nil
+	return reconcilation{info: info, apps: apps, states: states}, nil
 }
 
-// reconcileApp reconciles a single app
-func (c *Controller) reconcileApp(ctx context.Context, app App, info *reconcilationInfo) error {
-	name := app.GetName()
-	namespace := app.GetNamespace()
-	kind := app.GetKind()
+// reconcileApp reconciles a single app within the limits of the rule
+// strategies and returns its state
+func (c *Controller) reconcileApp(ctx context.Context, app App, info *reconcilationInfo, limits *ruleLimits) (state server.App, err error) {
+	ctx, span := startAppSpan(ctx, "reconcileApp", app)
+	defer func() {
+		span.SetAttributes(attribute.String("app.status", state.Status))
+		endSpan(span, err)
+	}()
+	logger := c.appLogger(app)
 
-	logger := c.Logger.With(
-		zap.Any("app", map[string]string{
-			"name":      name,
-			"namespace": namespace,
-			"kind":      kind,
-		}),
-	)
+	now := time.Now()
+	state, err = c.evaluate(app, now)
+	if err != nil {
+		return state, err
+	}
+	defer func() {
+		c.notifyOverdue(state, now)
+	}()
 
-	// First check for exclusion and then for inclusion
-	if shouldSelect(app, c.Cfg.Exclude, false) ||
-		!shouldSelect(app, c.Cfg.Include, true) {
+	switch state.Status {
+	case statusExcluded:
 		info.Excluded++
 		logger.Debug("Excluded")
-		return nil
+		return state, nil
+	case statusPostponed:
+		info.Skipped++
+		logger.Debug("postponed...skipping")
+		return state, nil
+	case statusNotReady:
+		info.Skipped++
+		logger.Debug("not ready...skipping")
+		return state, nil
+	case statusScheduled:
+		info.Skipped++
+		logger.Debug("not scheduled for a restart")
+		return state, nil
+	case statusOutsideWindow:
+		info.Skipped++
+		logger.Debug("outside restart window...deferring")
+		return state, nil
 	}
 
-	// Check for status
-	if !app.StatusOK() {
+	if info.Paused {
+		state.Status = statusDeferredPaused
 		info.Skipped++
-		logger.Debug("not ready...skipping")
-		return nil
+		logger.Debug("paused...deferring")
+		return state, nil
+	}
+
+	if status := limits.check(state.Matcher); status != "" {
+		state.Status = status
+		info.Skipped++
+		logger.Debug("limited by the strategy...deferring", zap.String("status", status))
+		return state, nil
+	}
+
+	err = c.restart(ctx, app, &state, triggerReconcile, "due since "+state.NextRestart.Format(time.RFC3339))
+	if err != nil {
+		return state, err
+	}
+	logger.Debug("restarted")
+	limits.add(state.Matcher)
+	info.Restarted++
+	return state, nil
+}
+
+// listApps lists all apps in the namespace or in all namespaces
+func (c *Controller) listApps(ctx context.Context) ([]App, error) {
+	var deployments *appv1.DeploymentList
+	err := observeAPI("deployments", "list", func() (err error) {
+		deployments, err = c.Clientset.AppsV1().Deployments(c.Namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployments, %w", err)
+	}
+	var statefulsets *appv1.StatefulSetList
+	err = observeAPI("statefulsets", "list", func() (err error) {
+		statefulsets, err = c.Clientset.AppsV1().StatefulSets(c.Namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statefulsets, %w", err)
+	}
+	var daemonsets *appv1.DaemonSetList
+	err = observeAPI("daemonsets", "list", func() (err error) {
+		daemonsets, err = c.Clientset.AppsV1().DaemonSets(c.Namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daemonsets, %w", err)
+	}
+
+	apps := make([]App, 0, len(deployments.Items)+len(statefulsets.Items)+len(daemonsets.Items))
+	for i := range deployments.Items {
+		apps = append(apps, (*Deployment)(&deployments.Items[i]))
+	}
+	for i := range statefulsets.Items {
+		apps = append(apps, (*StatefulSet)(&statefulsets.Items[i]))
 	}
+	for i := range daemonsets.Items {
+		apps = append(apps, (*DaemonSet)(&daemonsets.Items[i]))
+	}
+
+	return apps, nil
+}
+
+// evaluate runs the selection, status and age checks on an app and returns
+// its state. If the app is due for a restart, the status is statusDue.
+func (c *Controller) evaluate(app App, now time.Time) (server.App, error) {
+	name := app.GetName()
+	namespace := app.GetNamespace()
+	kind := app.GetKind()
+
+	state := server.App{
+		Namespace: namespace,
+		Kind:      kind,
+		Name:      name,
+	}
+	cfg := c.config()
+
+	// The first matching rule decides
+	rule, ok, err := cfg.Match(c.target(app))
+	if err != nil {
+		return state, fmt.Errorf("failed to match %v %v/%v, %w", kind, namespace, name, err)
+	}
+	if !ok {
+		state.Status = statusExcluded
+		return state, nil
+	}
+	state.Matcher = rule.Name
+	state.Selector = rule.Selector
+	if rule.Action == config.ActionIgnore {
+		state.Status = statusExcluded
+		return state, nil
+	}
+	state.Selected = true
 
 	// Check for age
-	now := time.Now()
 	last, err := getTimePodTemplateSpec(app.GetPodTemplateSpec())
 	if err != nil {
-		return fmt.Errorf("failed to get time from pod template from %v %v/%v, %w", kind, namespace, name, err)
+		return state, fmt.Errorf("failed to get time from pod template from %v %v/%v, %w", kind, namespace, name, err)
 	}
-	if last == nil {
+	if last != nil {
+		state.LastRestart = last
+	} else {
 		t := app.GetCreationTimestamp().Time
 		last = &t
 	}
-	if last.Add(c.Cfg.RestartInterval).After(now) {
-		logger.Debug("not scheduled for a restart")
-		info.Skipped++
-		return nil
+	next := rule.NextRestart(*last)
+	if next.IsZero() {
+		return state, fmt.Errorf("schedule of rule %v never matches", rule.Name)
 	}
+	state.NextRestart = &next
 
-	setTimeInPodTemplateSpec(app.GetPodTemplateSpec())
-	err = app.Update(context.TODO(), c.Clientset)
+	// Check for postponement
+	until, err := getPostponedUntil(app)
 	if err != nil {
-		return fmt.Errorf("failed to set annotations on pod template from %v %v/%v, %w", kind, namespace, name, err)
+		return state, fmt.Errorf("failed to get postponement from %v %v/%v, %w", kind, namespace, name, err)
+	}
+	postponed := until != nil && until.After(next)
+	if postponed {
+		state.NextRestart = until
 	}
 
-	logger.Debug("restarted")
-	info.Restarted++
+	// Check for status before the postponement, so that a postponed app,
+	// which is not ready, is reported and can not be restarted on request
+	if !app.StatusOK() {
+		state.Status = statusNotReady
+		return state, nil
+	}
+
+	if postponed && until.After(now) {
+		state.Status = statusPostponed
+		return state, nil
+	}
+
+	if state.NextRestart.After(now) {
+		state.Status = statusScheduled
+		return state, nil
+	}
+
+	if rule.Window != nil && !rule.Window.Contains(now) {
+		state.Status = statusOutsideWindow
+		return state, nil
+	}
+
+	state.Status = statusDue
+	return state, nil
+}
+
+// target returns the app as target of the selectors
+func (c *Controller) target(app App) config.Target {
+	labels, ok := c.namespaceLabels(app.GetNamespace())
+	t := newTarget(app, labels)
+	t.NamespaceUnknown = !ok
+	return t
+}
+
+// nextRestart returns the next restart of the app restarted at last by the
+// rule matching the app
+func (c *Controller) nextRestart(app App, last time.Time) time.Time {
+	cfg := c.config()
+	rule, ok, err := cfg.Match(c.target(app))
+	if err != nil || !ok {
+		return last.Add(cfg.RestartInterval)
+	}
+	return rule.NextRestart(last)
+}
+
+// restart restarts an app by setting the restartAtAnnotation and updates the
+// state accordingly. The trigger describes, what caused the restart, and the
+// reason is recorded in the audit log.
+func (c *Controller) restart(ctx context.Context, app App, state *server.App, trigger, reason string) error {
+	event := notify.Event{
+		Type:      notify.EventStarted,
+		Namespace: app.GetNamespace(),
+		Kind:      app.GetKind(),
+		Name:      app.GetName(),
+		Trigger:   trigger,
+	}
+	c.Notifier.Notify(event)
+
+	patch, err := restartPatch(time.Now())
+	if err != nil {
+		return err
+	}
+	err = observeAPI(resourceName(app), "patch", func() error {
+		return app.Patch(ctx, c.Clientset, patch)
+	})
+	c.audit(ctx, app, audit.DecisionRestart, trigger, reason, patch, err)
+	if err != nil {
+		err = fmt.Errorf("failed to set annotations on pod template from %v %v/%v, %w", app.GetKind(), app.GetNamespace(), app.GetName(), err)
+		event.Type = notify.EventFailed
+		event.Time = time.Time{}
+		event.Error = err.Error()
+		c.Notifier.Notify(event)
+		return err
+	}
+	event.Type = notify.EventSucceeded
+	event.Time = time.Time{}
+	c.Notifier.Notify(event)
+
+	last, err := getTimePodTemplateSpec(app.GetPodTemplateSpec())
+	if err != nil {
+		return fmt.Errorf("failed to get time from pod template from %v %v/%v, %w", app.GetKind(), app.GetNamespace(), app.GetName(), err)
+	}
+	if last == nil {
+		return fmt.Errorf("missing time in pod template from %v %v/%v after restart", app.GetKind(), app.GetNamespace(), app.GetName())
+	}
+	next := c.nextRestart(app, *last)
+	state.LastRestart = last
+	state.NextRestart = &next
+	state.Status = statusRestarted
+	state.Error = ""
+	opsRestartsTotal.WithLabelValues(app.GetNamespace(), app.GetKind(), app.GetName(), trigger).Inc()
+	opsLastRestart.WithLabelValues(app.GetNamespace(), app.GetKind(), app.GetName()).Set(float64(last.Unix()))
+	// There is no server when running once or as command line tool
+	if c.Server != nil {
+		c.Server.AddRestart(server.Restart{
+			Time:      *last,
+			Namespace: app.GetNamespace(),
+			Kind:      app.GetKind(),
+			Name:      app.GetName(),
+			Trigger:   trigger,
+		})
+	}
 	return nil
 }
 
+// notifyOverdue notifies about an app which is overdue for a restart, but
+// was skipped. Every overdue restart is only notified once.
+func (c *Controller) notifyOverdue(state server.App, now time.Time) {
+	if c.overdue == nil {
+		c.overdue = make(map[[3]string]time.Time)
+	}
+	key := [3]string{state.Namespace, state.Kind, state.Name}
+	if state.Status != statusNotReady && state.Status != statusDeferredPaused ||
+		state.NextRestart == nil || state.NextRestart.After(now) {
+		delete(c.overdue, key)
+		return
+	}
+	if notified, ok := c.overdue[key]; ok && notified.Equal(*state.NextRestart) {
+		return
+	}
+	c.overdue[key] = *state.NextRestart
+	c.Notifier.Notify(notify.Event{
+		Type:      notify.EventSkippedOverdue,
+		Namespace: state.Namespace,
+		Kind:      state.Kind,
+		Name:      state.Name,
+		Trigger:   triggerReconcile,
+		Reason:    state.Status,
+	})
+}
+
+// audit records a mutating action on an app in the audit log
+func (c *Controller) audit(ctx context.Context, app App, decision, trigger, reason string, patch []byte, err error) {
+	auditErr := c.Audit.Record(audit.Record{
+		Namespace: app.GetNamespace(),
+		Kind:      app.GetKind(),
+		Name:      app.GetName(),
+		Decision:  decision,
+		Trigger:   trigger,
+		User:      audit.UserFrom(ctx),
+		Reason:    reason,
+		Patch:     patch,
+	}, err)
+	if auditErr != nil {
+		c.appLogger(app).Sugar().Errorw("Failed to write audit log", "error", auditErr)
+	}
+}
+
+// appLogger returns a logger with the app attached
+func (c *Controller) appLogger(app App) *zap.Logger {
+	return c.Logger.With(
+		zap.Any("app", map[string]string{
+			"name":      app.GetName(),
+			"namespace": app.GetNamespace(),
+			"kind":      app.GetKind(),
+		}),
+	)
+}
+
 type selectable interface {
 	GetNamespace() string
+	GetKind() string
+	GetName() string
 	GetLabels() map[string]string
+	GetOwnerReferences() []metav1.OwnerReference
+	GetPodTemplateSpec() *v1.PodTemplateSpec
 }
 
-func shouldSelect(s selectable, matcher config.Matcher, defaultValue bool) bool {
-	// Mather is not enabled, so everything matches
-	if !matcher.Enabled {
-		return defaultValue
+// newTarget returns the selectable as target of the selectors
+func newTarget(s selectable, namespaceLabels map[string]string) config.Target {
+	return config.Target{
+		Namespace:       s.GetNamespace(),
+		Kind:            s.GetKind(),
+		Name:            s.GetName(),
+		Labels:          s.GetLabels(),
+		NamespaceLabels: namespaceLabels,
+		OwnerReferences: s.GetOwnerReferences(),
+		Images:          podImages(s.GetPodTemplateSpec()),
+		Object:          s,
 	}
-
-	for _, selector := range matcher.Selectors {
-		// Only check further if namespace either is empty or matches
-		if selector.Namespace != "" && s.GetNamespace() != selector.Namespace {
-			continue
-		}
-		// No MatchLabels or Matchlabels match
-		if len(selector.MatchLabels) == 0 ||
-			matchLabels(s.GetLabels(), selector.MatchLabels) {
-			return true
-		}
-	}
-	return false
 }
 
-// matchLabels checks if match are in labels.
-func matchLabels(labels, match map[string]string) bool {
-	for k, v := range match {
-		if l, ok := labels[k]; !ok || l != v {
-			return false
-		}
+// podImages returns the images of the init containers and containers
+func podImages(pts *v1.PodTemplateSpec) []string {
+	if pts == nil {
+		return nil
+	}
+	images := make([]string, 0, len(pts.Spec.InitContainers)+len(pts.Spec.Containers))
+	for _, c := range pts.Spec.InitContainers {
+		images = append(images, c.Image)
 	}
-	return true
+	for _, c := range pts.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
 }
 
 // getTimePodTemplateSpec get the restartAtAnnotation from a PodTemplateSpec.
@@ -223,10 +633,52 @@ func getTimePodTemplateSpec(pts *v1.PodTemplateSpec) (*time.Time, error) {
 	return &t, err
 }
 
-// setTimeInPodTemplateSpec sets the restartAtAnnotation on a PodTemplateSpec
-func setTimeInPodTemplateSpec(pts *v1.PodTemplateSpec) {
-	if pts.Annotations == nil {
-		pts.Annotations = make(map[string]string)
+// restartPatch returns a JSON merge patch setting the restartAtAnnotation on
+// the PodTemplateSpec of an app
+func restartPatch(t time.Time) ([]byte, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: t.Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restart patch, %w", err)
+	}
+	return patch, nil
+}
+
+// getPostponedUntil get the postponedUntilAnnotation from an app. If not set,
+// returns nil
+func getPostponedUntil(app App) (*time.Time, error) {
+	s, ok := app.GetAnnotations()[postponedUntilAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse time string %v, %w", s, err)
+	}
+	return &t, nil
+}
+
+// postponePatch returns a JSON merge patch setting the
+// postponedUntilAnnotation on an app
+func postponePatch(until time.Time) ([]byte, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				postponedUntilAnnotation: until.Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postpone patch, %w", err)
 	}
-	pts.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+	return patch, nil
 }
@@ -1,121 +1,255 @@
 package controller
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/shaardie/k8s-restarter/pkg/config"
+	"go.uber.org/zap"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
-type testSelectable struct {
-	Namespace string
-	Labels    map[string]string
-}
-
-func (ts testSelectable) GetNamespace() string {
-	return ts.Namespace
-}
-func (ts testSelectable) GetLabels() map[string]string {
-	return ts.Labels
+func Test_StatusOK(t *testing.T) {
+	tests := []struct {
+		name string
+		app  App
+		want bool
+	}{
+		{
+			name: "StatefulSet ready",
+			app: &StatefulSet{Status: appv1.StatefulSetStatus{
+				Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 3, AvailableReplicas: 3,
+			}},
+			want: true,
+		},
+		{
+			name: "StatefulSet updating",
+			app: &StatefulSet{Status: appv1.StatefulSetStatus{
+				Replicas: 3, UpdatedReplicas: 1, ReadyReplicas: 3, AvailableReplicas: 3,
+			}},
+			want: false,
+		},
+		{
+			name: "StatefulSet not available",
+			app: &StatefulSet{Status: appv1.StatefulSetStatus{
+				Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 3, AvailableReplicas: 2,
+			}},
+			want: false,
+		},
+		{
+			name: "DaemonSet ready",
+			app: &DaemonSet{Status: appv1.DaemonSetStatus{
+				DesiredNumberScheduled: 2, NumberAvailable: 2, NumberReady: 2,
+			}},
+			want: true,
+		},
+		{
+			name: "DaemonSet misscheduled",
+			app: &DaemonSet{Status: appv1.DaemonSetStatus{
+				DesiredNumberScheduled: 2, NumberAvailable: 2, NumberReady: 2, NumberMisscheduled: 1,
+			}},
+			want: false,
+		},
+		{
+			name: "DaemonSet unavailable",
+			app: &DaemonSet{Status: appv1.DaemonSetStatus{
+				DesiredNumberScheduled: 2, NumberAvailable: 1, NumberReady: 1, NumberUnavailable: 1,
+			}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.app.StatusOK(); got != tt.want {
+				t.Errorf("StatusOK() = %v, want %v", got, tt.want)
+			}
+		})
+	}
 }
 
-func Test_shouldSelect(t *testing.T) {
-	type args struct {
-		s            selectable
-		matcher      config.Matcher
-		defaultValue bool
-	}
+func Test_RolloutComplete(t *testing.T) {
+	replicas := int32(2)
 	tests := []struct {
 		name string
-		args args
+		app  App
 		want bool
 	}{
 		{
-			name: "Matcher not enabled",
-			args: args{
-				s:            testSelectable{},
-				matcher:      config.Matcher{Enabled: false},
-				defaultValue: true,
+			name: "StatefulSet rolled out",
+			app: &StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appv1.StatefulSetSpec{Replicas: &replicas},
+				Status: appv1.StatefulSetStatus{
+					ObservedGeneration: 2, UpdatedReplicas: 2, ReadyReplicas: 2,
+					CurrentRevision: "db-2", UpdateRevision: "db-2",
+				},
 			},
 			want: true,
 		},
 		{
-			name: "Namespace match",
-			args: args{
-				s: testSelectable{Namespace: "namespace"},
-				matcher: config.Matcher{
-					Enabled:   true,
-					Selectors: []config.Selector{{Namespace: "namespace"}},
+			name: "StatefulSet generation not observed",
+			app: &StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       appv1.StatefulSetSpec{Replicas: &replicas},
+				Status: appv1.StatefulSetStatus{
+					ObservedGeneration: 2, UpdatedReplicas: 2, ReadyReplicas: 2,
+					CurrentRevision: "db-2", UpdateRevision: "db-2",
 				},
-				defaultValue: false,
 			},
-			want: true,
+			want: false,
 		},
 		{
-			name: "Namespace does not match",
-			args: args{
-				s: testSelectable{Namespace: "other namespace"},
-				matcher: config.Matcher{
-					Enabled:   true,
-					Selectors: []config.Selector{{Namespace: "namespace"}},
+			name: "StatefulSet revision pending",
+			app: &StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appv1.StatefulSetSpec{Replicas: &replicas},
+				Status: appv1.StatefulSetStatus{
+					ObservedGeneration: 2, UpdatedReplicas: 2, ReadyReplicas: 2,
+					CurrentRevision: "db-1", UpdateRevision: "db-2",
 				},
-				defaultValue: true,
 			},
 			want: false,
 		},
 		{
-			name: "Labels match",
-			args: args{
-				s: testSelectable{
-					Labels: map[string]string{
-						"label1": "label1",
-						"label2": "label2",
-						"label3": "label3",
-					},
-				},
-				matcher: config.Matcher{
-					Enabled: true,
-					Selectors: []config.Selector{
-						{
-							MatchLabels: map[string]string{
-								"label1": "label1",
-								"label2": "label2",
-							},
-						},
-					},
+			name: "StatefulSet defaults to one replica",
+			app: &StatefulSet{Status: appv1.StatefulSetStatus{
+				UpdatedReplicas: 1, ReadyReplicas: 1,
+			}},
+			want: true,
+		},
+		{
+			name: "DaemonSet rolled out",
+			app: &DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status: appv1.DaemonSetStatus{
+					ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3,
 				},
-				defaultValue: false,
 			},
 			want: true,
 		},
 		{
-			name: "Labels does not match",
-			args: args{
-				s: testSelectable{
-					Labels: map[string]string{
-						"label1": "label1",
-						"label3": "label3",
-					},
+			name: "DaemonSet updating",
+			app: &DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status: appv1.DaemonSetStatus{
+					ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 2, NumberAvailable: 3,
 				},
-				matcher: config.Matcher{
-					Enabled: true,
-					Selectors: []config.Selector{
-						{
-							MatchLabels: map[string]string{
-								"label1": "label1",
-								"label2": "label2",
-							},
-						},
-					},
-				},
-				defaultValue: true,
 			},
 			want: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := shouldSelect(tt.args.s, tt.args.matcher, tt.args.defaultValue); got != tt.want {
-				t.Errorf("shouldSelect() = %v, want %v", got, tt.want)
+			if got := tt.app.RolloutComplete(); got != tt.want {
+				t.Errorf("RolloutComplete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Patch(t *testing.T) {
+	meta := metav1.ObjectMeta{Namespace: "default", Name: "app"}
+	tests := []struct {
+		name string
+		app  App
+	}{
+		{"Deployment", &Deployment{ObjectMeta: meta}},
+		{"StatefulSet", &StatefulSet{ObjectMeta: meta}},
+		{"DaemonSet", &DaemonSet{ObjectMeta: meta}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				&appv1.Deployment{ObjectMeta: meta},
+				&appv1.StatefulSet{ObjectMeta: meta},
+				&appv1.DaemonSet{ObjectMeta: meta},
+			)
+			err := tt.app.Patch(context.Background(), clientset, []byte(`{"spec":{"template":{"metadata":{"annotations":{"test":"patched"}}}}}`))
+			if err != nil {
+				t.Fatalf("Patch() error = %v", err)
+			}
+			if got := tt.app.GetPodTemplateSpec().Annotations["test"]; got != "patched" {
+				t.Errorf("Patch() did not update the app, annotation = %q", got)
+			}
+		})
+	}
+
+	missing := &StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "missing"}}
+	if err := missing.Patch(context.Background(), fake.NewSimpleClientset(), []byte(`{}`)); err == nil {
+		t.Errorf("Patch() of a missing app succeeded")
+	}
+}
+
+func TestController_SetConfig(t *testing.T) {
+	old := &config.Config{RestartInterval: time.Hour}
+	c := &Controller{Logger: zap.NewNop(), Cfg: old}
+
+	missingSecret := &config.Config{Notifications: config.Notifications{
+		Webhooks: []config.Webhook{{URL: "https://chat.example.com/hook", SecretFile: filepath.Join(t.TempDir(), "missing")}},
+	}}
+	if err := c.SetConfig(missingSecret); err == nil {
+		t.Fatal("SetConfig() succeeded with missing secret file")
+	}
+	c.applyConfig()
+	if c.config() != old {
+		t.Fatal("applyConfig() applied a rejected configuration")
+	}
+
+	cfg := &config.Config{RestartInterval: 2 * time.Hour}
+	if err := c.SetConfig(cfg); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+	if c.config() != old {
+		t.Fatal("SetConfig() applied the configuration before the next reconcilation")
+	}
+	c.applyConfig()
+	if c.config() != cfg {
+		t.Fatal("applyConfig() did not apply the pending configuration")
+	}
+}
+
+func TestController_evaluateSchedule(t *testing.T) {
+	created := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	// Every Sunday at 03:00, the first after the creation is on June 5
+	c := &Controller{Cfg: &config.Config{Rules: []config.Rule{{Schedule: &config.Schedule{Cron: "0 3 * * 0"}}}}}
+	d := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         "default",
+		Name:              "web",
+		CreationTimestamp: metav1.NewTime(created),
+	}}
+	d.Status.Replicas = 1
+	d.Status.UpdatedReplicas = 1
+	app := (*Deployment)(d)
+	lister, err := staticNamespaceLister(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.namespaces = lister
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		wantStatus string
+	}{
+		{"before", time.Date(2022, 6, 5, 2, 59, 0, 0, time.UTC), statusScheduled},
+		{"at", time.Date(2022, 6, 5, 3, 0, 0, 0, time.UTC), statusDue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := c.evaluate(app, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Status != tt.wantStatus {
+				t.Errorf("evaluate() status = %v, want %v", state.Status, tt.wantStatus)
+			}
+			want := time.Date(2022, 6, 5, 3, 0, 0, 0, time.UTC)
+			if state.NextRestart == nil || !state.NextRestart.Equal(want) {
+				t.Errorf("evaluate() next restart = %v, want %v", state.NextRestart, want)
 			}
 		})
 	}
@@ -7,6 +7,7 @@ import (
 	appv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -24,14 +25,25 @@ func (d *DaemonSet) StatusOK() bool {
 		d.Status.DesiredNumberScheduled == d.Status.NumberReady
 }
 
+func (d *DaemonSet) RolloutComplete() bool {
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedNumberScheduled == d.Status.DesiredNumberScheduled &&
+		d.Status.NumberAvailable == d.Status.DesiredNumberScheduled
+}
+
 func (d *DaemonSet) GetPodTemplateSpec() *v1.PodTemplateSpec {
 	return &d.Spec.Template
 }
 
-func (d *DaemonSet) Update(ctx context.Context, clientset *kubernetes.Clientset) error {
-	_, err := clientset.AppsV1().DaemonSets(d.Namespace).Update(context.TODO(), (*appv1.DaemonSet)(d), metav1.UpdateOptions{})
+func (d *DaemonSet) Patch(ctx context.Context, clientset kubernetes.Interface, patch []byte) (err error) {
+	ctx, span := startAppSpan(ctx, "DaemonSet.Patch", d)
+	defer func() {
+		endSpan(span, err)
+	}()
+	result, err := clientset.AppsV1().DaemonSets(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to update %v %v/%v, %v", d.GetKind(), d.GetNamespace(), d.GetName(), err)
+		return fmt.Errorf("failed to patch %v %v/%v, %w", d.GetKind(), d.GetNamespace(), d.GetName(), err)
 	}
+	*d = DaemonSet(*result)
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	appv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -22,14 +23,30 @@ func (d *Deployment) StatusOK() bool {
 		d.Status.ReadyReplicas == d.Status.AvailableReplicas
 }
 
+func (d *Deployment) RolloutComplete() bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == d.Status.UpdatedReplicas &&
+		d.Status.AvailableReplicas == d.Status.UpdatedReplicas
+}
+
 func (d *Deployment) GetPodTemplateSpec() *v1.PodTemplateSpec {
 	return &d.Spec.Template
 }
 
-func (d *Deployment) Update(ctx context.Context, clientset *kubernetes.Clientset) error {
-	_, err := clientset.AppsV1().Deployments(d.Namespace).Update(context.TODO(), (*appv1.Deployment)(d), metav1.UpdateOptions{})
+func (d *Deployment) Patch(ctx context.Context, clientset kubernetes.Interface, patch []byte) (err error) {
+	ctx, span := startAppSpan(ctx, "Deployment.Patch", d)
+	defer func() {
+		endSpan(span, err)
+	}()
+	result, err := clientset.AppsV1().Deployments(d.Namespace).Patch(ctx, d.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to update %v %v/%v, %v", d.GetKind(), d.GetNamespace(), d.GetName(), err)
+		return fmt.Errorf("failed to patch %v %v/%v, %w", d.GetKind(), d.GetNamespace(), d.GetName(), err)
 	}
+	*d = Deployment(*result)
 	return nil
 }
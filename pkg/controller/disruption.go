@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shaardie/k8s-restarter/pkg/server"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// checkDisruptionBudgets rejects the restart of an app, if a
+// PodDisruptionBudget selecting its Pods currently allows no disruption
+func (c *Controller) checkDisruptionBudgets(ctx context.Context, app App) error {
+	var pdbs *policyv1.PodDisruptionBudgetList
+	err := observeAPI("poddisruptionbudgets", "list", func() (err error) {
+		pdbs, err = c.Clientset.PolicyV1().PodDisruptionBudgets(app.GetNamespace()).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get poddisruptionbudgets in %v, %w", app.GetNamespace(), err)
+	}
+	return blockingDisruptionBudget(pdbs.Items, app)
+}
+
+// blockingDisruptionBudget returns an error for the first disruption budget
+// selecting the Pods of the app, which allows no disruption
+func blockingDisruptionBudget(pdbs []policyv1.PodDisruptionBudget, app App) error {
+	podLabels := labels.Set(app.GetPodTemplateSpec().Labels)
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !selector.Matches(podLabels) {
+			continue
+		}
+		if pdb.Status.DisruptionsAllowed < 1 {
+			return fmt.Errorf("%w, poddisruptionbudget %v allows no disruption of %v %v/%v", server.ErrRejected, pdb.Name, app.GetKind(), app.GetNamespace(), app.GetName())
+		}
+	}
+	return nil
+}
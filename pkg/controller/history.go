@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	kubectlRestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// Causes of a revision
+const (
+	CauseRestarter = "k8s-restarter"
+	CauseKubectl   = "kubectl rollout restart"
+	CauseChange    = "change"
+	CauseUnknown   = "-"
+)
+
+// Revision is a revision of the PodTemplateSpec of an app
+type Revision struct {
+	Revision int64     `json:"revision"`
+	Created  time.Time `json:"created"`
+	// Cause is what changed compared to the previous revision
+	Cause       string     `json:"cause"`
+	RestartedAt *time.Time `json:"restartedAt,omitempty"`
+}
+
+// History returns the revisions of an app still kept by Kubernetes in order,
+// i.e. the ReplicaSets of a Deployment and the ControllerRevisions of a
+// StatefulSet or DaemonSet
+func (c *Controller) History(ctx context.Context, namespace, kind, name string) ([]Revision, error) {
+	app, err := c.getApp(ctx, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	var selector *metav1.LabelSelector
+	switch a := app.(type) {
+	case *Deployment:
+		selector = a.Spec.Selector
+	case *StatefulSet:
+		selector = a.Spec.Selector
+	case *DaemonSet:
+		selector = a.Spec.Selector
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of %v %v/%v, %w", kind, namespace, name, err)
+	}
+	opts := metav1.ListOptions{LabelSelector: s.String()}
+
+	type revision struct {
+		Revision
+		annotations map[string]string
+	}
+	var revisions []revision
+	if _, ok := app.(*Deployment); ok {
+		rss, err := c.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get replicasets of %v %v/%v, %w", kind, namespace, name, err)
+		}
+		for i := range rss.Items {
+			rs := &rss.Items[i]
+			if !metav1.IsControlledBy(rs, app) {
+				continue
+			}
+			n, _ := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+			revisions = append(revisions, revision{
+				Revision:    Revision{Revision: n, Created: rs.CreationTimestamp.Time},
+				annotations: rs.Spec.Template.Annotations,
+			})
+		}
+	} else {
+		crs, err := c.Clientset.AppsV1().ControllerRevisions(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get controllerrevisions of %v %v/%v, %w", kind, namespace, name, err)
+		}
+		for i := range crs.Items {
+			cr := &crs.Items[i]
+			if !metav1.IsControlledBy(cr, app) {
+				continue
+			}
+			annotations, err := revisionAnnotations(cr)
+			if err != nil {
+				return nil, err
+			}
+			revisions = append(revisions, revision{
+				Revision:    Revision{Revision: cr.Revision, Created: cr.CreationTimestamp.Time},
+				annotations: annotations,
+			})
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision.Revision < revisions[j].Revision.Revision
+	})
+
+	history := make([]Revision, 0, len(revisions))
+	for i, r := range revisions {
+		if s, ok := r.annotations[restartedAtAnnotation]; ok {
+			t, err := time.Parse(time.RFC3339, s)
+			if err == nil {
+				r.RestartedAt = &t
+			}
+		}
+		switch {
+		case i == 0:
+			r.Cause = CauseUnknown
+		case r.annotations[restartedAtAnnotation] != revisions[i-1].annotations[restartedAtAnnotation]:
+			r.Cause = CauseRestarter
+		case r.annotations[kubectlRestartedAtAnnotation] != revisions[i-1].annotations[kubectlRestartedAtAnnotation]:
+			r.Cause = CauseKubectl
+		default:
+			r.Cause = CauseChange
+		}
+		history = append(history, r.Revision)
+	}
+	return history, nil
+}
+
+// revisionAnnotations returns the annotations of the PodTemplateSpec stored
+// in a ControllerRevision
+func revisionAnnotations(cr *appv1.ControllerRevision) (map[string]string, error) {
+	var data struct {
+		Spec struct {
+			Template struct {
+				Metadata metav1.ObjectMeta `json:"metadata"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	err := json.Unmarshal(cr.Data.Raw, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse controllerrevision %v/%v, %w", cr.Namespace, cr.Name, err)
+	}
+	return data.Spec.Template.Metadata.Annotations, nil
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"testing"
+
+	appv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Test_revisionAnnotations(t *testing.T) {
+	cr := &appv1.ControllerRevision{
+		Data: runtime.RawExtension{Raw: []byte(`{"spec":{"template":{"$patch":"replace","metadata":{"annotations":{"k8s-restarter.kubernetes.io/restartedAt":"2022-06-01T02:00:00Z"}}}}}`)},
+	}
+	got, err := revisionAnnotations(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[restartedAtAnnotation] != "2022-06-01T02:00:00Z" {
+		t.Errorf("revisionAnnotations() = %v", got)
+	}
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	appv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Manifests are the apps and namespaces read from manifests
+type Manifests struct {
+	Apps       []App
+	Namespaces []*v1.Namespace
+}
+
+// LoadManifests reads the apps and namespaces from YAML or JSON files, e.g. a
+// dump of kubectl get -o yaml. Directories are read recursively. Lists are
+// flattened and all other kinds are ignored.
+func LoadManifests(paths ...string) (*Manifests, error) {
+	m := &Manifests{}
+	for _, path := range paths {
+		err := filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := filepath.Ext(name)
+			if info.IsDir() || name != path && ext != ".yaml" && ext != ".yml" && ext != ".json" {
+				return nil
+			}
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			err = m.read(f)
+			if err != nil {
+				return fmt.Errorf("failed to read manifests from %v, %w", name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// read reads all documents of a stream
+func (m *Manifests) read(r io.Reader) error {
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		err = m.decode(doc)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decode decodes a single document
+func (m *Manifests) decode(doc []byte) error {
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(doc, nil, nil)
+	if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return m.add(obj)
+}
+
+// add adds the object or the items of a list
+func (m *Manifests) add(obj runtime.Object) error {
+	switch o := obj.(type) {
+	case *appv1.Deployment:
+		m.Apps = append(m.Apps, (*Deployment)(o))
+	case *appv1.StatefulSet:
+		m.Apps = append(m.Apps, (*StatefulSet)(o))
+	case *appv1.DaemonSet:
+		m.Apps = append(m.Apps, (*DaemonSet)(o))
+	case *v1.Namespace:
+		m.Namespaces = append(m.Namespaces, o)
+	default:
+		if !meta.IsListType(obj) {
+			return nil
+		}
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if u, ok := item.(*runtime.Unknown); ok {
+				err = m.decode(u.Raw)
+			} else {
+				err = m.add(item)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
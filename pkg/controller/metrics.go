@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"strings"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shaardie/k8s-restarter/pkg/server"
 )
 
 var (
-	histoBuckets = []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
-	opsRestarts  = promauto.NewGauge(prometheus.GaugeOpts{
+	appLabels   = []string{"namespace", "kind", "name"}
+	opsRestarts = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "k8s_restarter_restarts",
 		Help: "The number of restarted apps in the last reconcilation",
 	})
@@ -19,19 +23,119 @@ var (
 		Name: "k8s_restarter_skips",
 		Help: "The number of skipped apps in the last reconcilation",
 	})
-	opsRestartsHisto = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "k8s_restarter_restarts_histo",
-		Help:    "The number of restarted apps",
-		Buckets: histoBuckets,
+	opsFailures = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "k8s_restarter_failures",
+		Help: "The number of apps failed to reconcile in the last reconcilation",
 	})
-	opsExcludedHisto = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "k8s_restarter_ignores_histo",
-		Help:    "The number of ignored apps",
-		Buckets: histoBuckets,
+	opsAppFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_restarter_app_failures_total",
+		Help: "The total number of failed reconcilations of an app",
+	}, appLabels)
+	opsReconcileDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "k8s_restarter_reconcile_duration_seconds",
+		Help:    "The duration of the reconcilations",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
 	})
-	opsSkipsHisto = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "k8s_restarter_skips_histo",
-		Help:    "The number of skipped apps",
-		Buckets: histoBuckets,
+	opsLastSuccessfulReconcile = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "k8s_restarter_last_successful_reconcile_timestamp_seconds",
+		Help: "The time of the last successful reconcilation in seconds since epoch",
 	})
+	opsAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "k8s_restarter_api_request_duration_seconds",
+		Help: "The latency of requests to the Kubernetes API by resource and verb",
+	}, []string{"resource", "verb"})
+	opsAPIErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_restarter_api_request_errors_total",
+		Help: "The total number of failed requests to the Kubernetes API by resource and verb",
+	}, []string{"resource", "verb"})
+	opsSkipsByReason = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_restarter_skips_by_reason",
+		Help: "The number of skipped apps in the last reconcilation by reason",
+	}, []string{"reason"})
+	opsRestartsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_restarter_restarts_total",
+		Help: "The total number of restarts of an app by reason",
+	}, append(appLabels, "reason"))
+	opsLastRestart = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_restarter_last_restart_timestamp_seconds",
+		Help: "The time of the last restart of an app in seconds since epoch",
+	}, appLabels)
+	opsNextRestart = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_restarter_next_restart_timestamp_seconds",
+		Help: "The time of the next restart of a selected app in seconds since epoch",
+	}, appLabels)
+
+	// skipReasons are the status of an app counted as skip
+	skipReasons = []string{statusPostponed, statusNotReady, statusScheduled, statusDeferredPaused, statusOutsideWindow,
+		statusDeferredConcurrency, statusDeferredBudget}
+	// restartTriggers are the values of the reason label of opsRestartsTotal
+	restartTriggers = []string{triggerReconcile, triggerAPI, triggerCLI}
+	// appMetrics holds the labels of the apps with per-app metrics, so that
+	// the metrics of vanished apps can be deleted
+	appMetrics = map[[3]string]struct{}{}
 )
+
+// updateAppMetrics updates the per-app metrics and the skip reasons from the
+// states of the last reconcilation. The per-app metrics of vanished apps and
+// of apps not selected anymore are deleted, so that the number of series is
+// bounded by the managed apps.
+func updateAppMetrics(states []server.App) {
+	skips := make(map[string]int, len(skipReasons))
+	current := make(map[[3]string]struct{}, len(states))
+	for _, state := range states {
+		skips[state.Status]++
+		labels := [3]string{state.Namespace, state.Kind, state.Name}
+		if !state.Selected && state.Status != statusFailed {
+			deleteAppMetrics(labels)
+			continue
+		}
+		current[labels] = struct{}{}
+		if state.LastRestart != nil {
+			opsLastRestart.WithLabelValues(labels[:]...).Set(float64(state.LastRestart.Unix()))
+		} else {
+			opsLastRestart.DeleteLabelValues(labels[:]...)
+		}
+		if state.Selected && state.NextRestart != nil {
+			opsNextRestart.WithLabelValues(labels[:]...).Set(float64(state.NextRestart.Unix()))
+		} else {
+			opsNextRestart.DeleteLabelValues(labels[:]...)
+		}
+	}
+	for labels := range appMetrics {
+		if _, ok := current[labels]; !ok {
+			deleteAppMetrics(labels)
+		}
+	}
+	appMetrics = current
+
+	for _, reason := range skipReasons {
+		opsSkipsByReason.WithLabelValues(reason).Set(float64(skips[reason]))
+	}
+}
+
+// deleteAppMetrics deletes all per-app metrics of an app
+func deleteAppMetrics(labels [3]string) {
+	opsLastRestart.DeleteLabelValues(labels[:]...)
+	opsNextRestart.DeleteLabelValues(labels[:]...)
+	opsAppFailures.DeleteLabelValues(labels[:]...)
+	for _, trigger := range restartTriggers {
+		opsRestartsTotal.DeleteLabelValues(append(labels[:], trigger)...)
+	}
+}
+
+// observeAPI observes the latency and the errors of a call to the Kubernetes
+// API
+func observeAPI(resource, verb string, call func() error) error {
+	start := time.Now()
+	err := call()
+	opsAPIDuration.WithLabelValues(resource, verb).Observe(time.Since(start).Seconds())
+	if err != nil {
+		opsAPIErrors.WithLabelValues(resource, verb).Inc()
+	}
+	return err
+}
+
+// resourceName returns the name of the Kubernetes resource of an app
+func resourceName(app App) string {
+	return strings.ToLower(app.GetKind()) + "s"
+}
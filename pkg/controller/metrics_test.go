@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shaardie/k8s-restarter/pkg/server"
+)
+
+func Test_updateAppMetrics(t *testing.T) {
+	last := time.Unix(1000, 0)
+	next := time.Unix(2000, 0)
+	// Other tests restart apps
+	opsLastRestart.Reset()
+	opsNextRestart.Reset()
+	opsAppFailures.Reset()
+	opsRestartsTotal.Reset()
+	opsAppFailures.WithLabelValues("ns", "Deployment", "a").Inc()
+	opsRestartsTotal.WithLabelValues("ns", "Deployment", "a", triggerAPI).Inc()
+	opsAppFailures.WithLabelValues("ns", "Deployment", "c").Inc()
+	updateAppMetrics([]server.App{
+		{Namespace: "ns", Kind: "Deployment", Name: "a", Selected: true, LastRestart: &last, NextRestart: &next, Status: statusScheduled},
+		{Namespace: "ns", Kind: "Deployment", Name: "b", Selected: true, NextRestart: &next, Status: statusNotReady},
+		{Namespace: "ns", Kind: "Deployment", Name: "c", Status: statusExcluded},
+	})
+
+	if got := testutil.ToFloat64(opsLastRestart.WithLabelValues("ns", "Deployment", "a")); got != 1000 {
+		t.Errorf("last restart = %v, want 1000", got)
+	}
+	if got := testutil.CollectAndCount(opsLastRestart); got != 1 {
+		t.Errorf("last restart series = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(opsNextRestart); got != 2 {
+		t.Errorf("next restart series = %v, want 2", got)
+	}
+	// Apps not selected anymore are removed
+	if got := testutil.CollectAndCount(opsAppFailures); got != 1 {
+		t.Errorf("app failure series = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(opsSkipsByReason.WithLabelValues(statusNotReady)); got != 1 {
+		t.Errorf("skips not ready = %v, want 1", got)
+	}
+
+	// Vanished apps are removed
+	updateAppMetrics([]server.App{
+		{Namespace: "ns", Kind: "Deployment", Name: "b", Selected: true, NextRestart: &next, Status: statusScheduled},
+	})
+	if got := testutil.CollectAndCount(opsLastRestart); got != 0 {
+		t.Errorf("last restart series = %v, want 0", got)
+	}
+	if got := testutil.CollectAndCount(opsNextRestart); got != 1 {
+		t.Errorf("next restart series = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(opsAppFailures); got != 0 {
+		t.Errorf("app failure series = %v, want 0", got)
+	}
+	if got := testutil.CollectAndCount(opsRestartsTotal); got != 0 {
+		t.Errorf("restart series = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(opsSkipsByReason.WithLabelValues(statusNotReady)); got != 0 {
+		t.Errorf("skips not ready = %v, want 0", got)
+	}
+}
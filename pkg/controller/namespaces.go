@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceSyncTimeout is the maximum time to wait for the namespace cache
+var namespaceSyncTimeout = 30 * time.Second
+
+// startNamespaceInformer starts an informer caching the Namespace objects,
+// whose labels are matched by the namespace selectors. It returns an error,
+// if the cache is not synced within namespaceSyncTimeout, e.g. since listing
+// the Namespaces is forbidden. The informer keeps running until the context
+// is done and the labels of namespaces missing in the cache are unknown.
+func (c *Controller) startNamespaceInformer(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactory(c.Clientset, 0)
+	informer := factory.Core().V1().Namespaces()
+	synced := informer.Informer().HasSynced
+	lister := informer.Lister()
+	factory.Start(ctx.Done())
+	c.m.Lock()
+	c.namespaces = lister
+	c.m.Unlock()
+
+	syncCtx, cancel := context.WithTimeout(ctx, namespaceSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), synced) {
+		return fmt.Errorf("failed to sync namespace cache within %v", namespaceSyncTimeout)
+	}
+	return nil
+}
+
+// loadNamespace gets a single Namespace object for the namespace selectors
+// instead of caching all. The labels of a Namespace, which can not be read,
+// are unknown.
+func (c *Controller) loadNamespace(ctx context.Context, name string) error {
+	var namespaces []*v1.Namespace
+	ns, err := c.Clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		namespaces = append(namespaces, ns)
+	case !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err):
+		return fmt.Errorf("failed to get namespace %v, %w", name, err)
+	}
+	lister, err := staticNamespaceLister(namespaces)
+	if err != nil {
+		return err
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.namespaces = lister
+	return nil
+}
+
+// staticNamespaceLister returns a lister of the namespaces
+func staticNamespaceLister(namespaces []*v1.Namespace) (corelisters.NamespaceLister, error) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range namespaces {
+		err := indexer.Add(ns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add namespace %v, %w", ns.Name, err)
+		}
+	}
+	return corelisters.NewNamespaceLister(indexer), nil
+}
+
+// namespaceLabels returns the labels of the namespace. Returns false, if the
+// namespace is unknown or the informer is not running.
+func (c *Controller) namespaceLabels(namespace string) (map[string]string, bool) {
+	c.m.Lock()
+	lister := c.namespaces
+	c.m.Unlock()
+	if lister == nil {
+		return nil, false
+	}
+	ns, err := lister.Get(namespace)
+	if err != nil {
+		return nil, false
+	}
+	return ns.Labels, true
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestController_evaluateNamespaceLabels(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	staging := &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "staging"}}
+	include := &config.Config{
+		RestartInterval: time.Hour,
+		Include:         config.Matcher{Enabled: true, Selectors: []config.Selector{{NamespaceSelector: staging}}},
+	}
+	exclude := &config.Config{
+		RestartInterval: time.Hour,
+		Exclude:         config.Matcher{Enabled: true, Selectors: []config.Selector{{NamespaceSelector: staging}}},
+	}
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "default",
+		Labels: map[string]string{"environment": "staging"},
+	}}
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		namespaces []*v1.Namespace
+		wantStatus string
+		wantErr    bool
+	}{
+		{"include known", include, []*v1.Namespace{namespace}, statusDue, false},
+		{"include unknown", include, nil, statusExcluded, false},
+		{"exclude known", exclude, []*v1.Namespace{namespace}, statusExcluded, false},
+		{"exclude unknown", exclude, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lister, err := staticNamespaceLister(tt.namespaces)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Controller{Cfg: tt.cfg, namespaces: lister}
+			app := &Deployment{ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              "web",
+				CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour)),
+			}}
+			app.Status.Replicas = 1
+			app.Status.UpdatedReplicas = 1
+			state, err := c.evaluate(app, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && state.Status != tt.wantStatus {
+				t.Errorf("evaluate() status = %v, want %v", state.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestController_startNamespaceInformer(t *testing.T) {
+	timeout := namespaceSyncTimeout
+	namespaceSyncTimeout = 100 * time.Millisecond
+	defer func() {
+		namespaceSyncTimeout = timeout
+	}()
+
+	tests := []struct {
+		name      string
+		forbidden bool
+		wantErr   bool
+	}{
+		{"synced", false, false},
+		{"forbidden", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+			if tt.forbidden {
+				clientset.PrependReactor("list", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "", nil)
+				})
+			}
+			c := &Controller{Clientset: clientset}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err := c.startNamespaceInformer(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("startNamespaceInformer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			_, ok := c.namespaceLabels("default")
+			if ok != !tt.wantErr {
+				t.Errorf("namespaceLabels() known = %v, want %v", ok, !tt.wantErr)
+			}
+		})
+	}
+}
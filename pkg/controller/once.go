@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/server"
+)
+
+// rolloutPollInterval is the interval to check the rollouts of restarted apps
+const rolloutPollInterval = 2 * time.Second
+
+// Report is the result of a single reconcilation run by Once
+type Report struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	reconcilationInfo
+	Apps  []server.App `json:"apps"`
+	Error string       `json:"error,omitempty"`
+}
+
+// OK returns, if the reconcilation and all restarts succeeded
+func (r *Report) OK() bool {
+	return r.Error == "" && r.reconcilationInfo.Failed == 0
+}
+
+// Once runs a single reconcilation without leader election, e.g. in a
+// CronJob. If rolloutTimeout is positive, it waits up to rolloutTimeout for
+// the rollouts of the restarted apps and incomplete rollouts count as
+// failures.
+func (c *Controller) Once(ctx context.Context, rolloutTimeout time.Duration) (report Report) {
+	report = Report{Start: time.Now(), Apps: []server.App{}}
+	defer func() {
+		report.End = time.Now()
+	}()
+	err := c.startNamespaceInformer(ctx)
+	if err != nil {
+		c.Logger.Sugar().Errorw("Failed to sync namespace cache, apps in namespaces with unknown labels are skipped by namespace selectors", "error", err)
+	}
+
+	result, err := c.reconcile(ctx)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.reconcilationInfo = result.info
+	report.Apps = result.states
+	if rolloutTimeout <= 0 {
+		return report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rolloutTimeout)
+	defer cancel()
+	for i, app := range result.apps {
+		if report.Apps[i].Status != statusRestarted {
+			continue
+		}
+		err := c.WaitForRollout(ctx, app, rolloutPollInterval)
+		if err != nil {
+			c.appLogger(app).Sugar().Errorw("Rollout did not complete", "error", err)
+			report.Apps[i].Status = statusRolloutFailed
+			report.Apps[i].Error = err.Error()
+			report.Failed++
+		}
+	}
+	return report
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"go.uber.org/zap"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		report Report
+		ok     bool
+	}{
+		{"restarted", Report{reconcilationInfo: reconcilationInfo{Restarted: 2, Skipped: 1}}, true},
+		{"failed restart", Report{reconcilationInfo: reconcilationInfo{Restarted: 1, Failed: 1}}, false},
+		{"failed reconcilation", Report{Error: "failed to list deployments"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.OK(); got != tt.ok {
+				t.Errorf("Report.OK() = %v, want %v", got, tt.ok)
+			}
+			b, err := json.Marshal(tt.report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The counters are at the top level of the report
+			if !strings.Contains(string(b), `"failed":`) {
+				t.Errorf("Report JSON %s misses the counters", b)
+			}
+		})
+	}
+}
+
+func TestController_Once(t *testing.T) {
+	deployment := func(name string, complete bool) *appv1.Deployment {
+		d := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              name,
+			Generation:        1,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+		}}
+		d.Status.ObservedGeneration = 1
+		d.Status.Replicas = 1
+		d.Status.UpdatedReplicas = 1
+		d.Status.ReadyReplicas = 1
+		d.Status.AvailableReplicas = 1
+		if !complete {
+			d.Generation = 2
+		}
+		return d
+	}
+	tests := []struct {
+		name           string
+		rolloutTimeout time.Duration
+		listErr        bool
+		wantStatus     map[string]string
+		wantFailed     int
+		ok             bool
+	}{
+		{
+			name:       "without waiting",
+			wantStatus: map[string]string{"done": statusRestarted, "slow": statusRestarted},
+			ok:         true,
+		},
+		{
+			name:           "rollout timeout",
+			rolloutTimeout: 100 * time.Millisecond,
+			wantStatus:     map[string]string{"done": statusRestarted, "slow": statusRolloutFailed},
+			wantFailed:     1,
+		},
+		{
+			name:    "failed reconcilation",
+			listErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(deployment("done", true), deployment("slow", false))
+			if tt.listErr {
+				clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("connection refused")
+				})
+			}
+			c := &Controller{
+				Cfg:       &config.Config{RestartInterval: time.Hour},
+				Clientset: clientset,
+				Logger:    zap.NewNop(),
+			}
+			report := c.Once(context.Background(), tt.rolloutTimeout)
+			if report.OK() != tt.ok {
+				t.Errorf("Once() OK = %v, want %v, report %+v", report.OK(), tt.ok, report)
+			}
+			if tt.listErr {
+				if report.Error == "" {
+					t.Errorf("Once() error is empty")
+				}
+				return
+			}
+			if report.Failed != tt.wantFailed {
+				t.Errorf("Once() failed = %v, want %v", report.Failed, tt.wantFailed)
+			}
+			for _, app := range report.Apps {
+				if app.Status != tt.wantStatus[app.Name] {
+					t.Errorf("Once() status of %v = %v, want %v", app.Name, app.Status, tt.wantStatus[app.Name])
+				}
+				d, err := clientset.AppsV1().Deployments("default").Get(context.Background(), app.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := d.Spec.Template.Annotations[restartedAtAnnotation]; !ok {
+					t.Errorf("Once() did not restart %v", app.Name)
+				}
+			}
+		})
+	}
+}
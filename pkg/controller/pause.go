@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/shaardie/k8s-restarter/pkg/audit"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const pausedKey = "paused"
+
+// IsPaused reads the pause state from the pause ConfigMap. A missing
+// ConfigMap means not paused.
+func (c *Controller) IsPaused(ctx context.Context) (bool, error) {
+	if c.PauseConfigMap == "" {
+		return false, nil
+	}
+	cm, err := c.Clientset.CoreV1().ConfigMaps(c.PauseNamespace).Get(ctx, c.PauseConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get pause configmap %v/%v, %w", c.PauseNamespace, c.PauseConfigMap, err)
+	}
+	s, ok := cm.Data[pausedKey]
+	if !ok {
+		return false, nil
+	}
+	paused, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %v in pause configmap %v/%v, %w", pausedKey, c.PauseNamespace, c.PauseConfigMap, err)
+	}
+	return paused, nil
+}
+
+// SetPaused pauses or resumes the automatic restarts by writing the pause
+// ConfigMap, so that the state survives a leader failover.
+func (c *Controller) SetPaused(ctx context.Context, paused bool) error {
+	if c.PauseConfigMap == "" {
+		return fmt.Errorf("no pause configmap configured")
+	}
+	cm, verb, err := c.writePaused(ctx, paused)
+	decision := audit.DecisionResume
+	if paused {
+		decision = audit.DecisionPause
+	}
+	record := audit.Record{
+		Namespace: c.PauseNamespace,
+		Kind:      "ConfigMap",
+		Name:      c.PauseConfigMap,
+		Decision:  decision,
+		Trigger:   triggerAPI,
+		User:      audit.UserFrom(ctx),
+		Reason:    verb,
+	}
+	if cm != nil {
+		record.Object, _ = json.Marshal(cm)
+	}
+	auditErr := c.Audit.Record(record, err)
+	if auditErr != nil {
+		c.Logger.Sugar().Errorw("Failed to write audit log", "error", auditErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.Server != nil {
+		c.Server.SetPaused(paused)
+	}
+	c.Logger.Sugar().Infow("Set pause state", "paused", paused)
+	return nil
+}
+
+// writePaused creates or updates the pause ConfigMap. It returns the
+// ConfigMap sent to the Kubernetes API, if any, and the verb used.
+func (c *Controller) writePaused(ctx context.Context, paused bool) (*v1.ConfigMap, string, error) {
+	cms := c.Clientset.CoreV1().ConfigMaps(c.PauseNamespace)
+	cm, err := cms.Get(ctx, c.PauseConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.PauseConfigMap,
+				Namespace: c.PauseNamespace,
+			},
+			Data: map[string]string{pausedKey: strconv.FormatBool(paused)},
+		}
+		_, err = cms.Create(ctx, cm, metav1.CreateOptions{})
+		if err != nil {
+			return cm, "create", fmt.Errorf("failed to create pause configmap %v/%v, %w", c.PauseNamespace, c.PauseConfigMap, err)
+		}
+		return cm, "create", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get pause configmap %v/%v, %w", c.PauseNamespace, c.PauseConfigMap, err)
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[pausedKey] = strconv.FormatBool(paused)
+	cm.ManagedFields = nil
+	_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+	if err != nil {
+		return cm, "update", fmt.Errorf("failed to update pause configmap %v/%v, %w", c.PauseNamespace, c.PauseConfigMap, err)
+	}
+	return cm, "update", nil
+}
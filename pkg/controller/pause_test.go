@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shaardie/k8s-restarter/pkg/audit"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestController_IsPaused(t *testing.T) {
+	pause := func(data map[string]string) runtime.Object {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "k8s-restarter", Name: "pause"},
+			Data:       data,
+		}
+	}
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    bool
+		wantErr bool
+	}{
+		{"missing configmap", nil, false, false},
+		{"missing key", []runtime.Object{pause(nil)}, false, false},
+		{"paused", []runtime.Object{pause(map[string]string{pausedKey: "true"})}, true, false},
+		{"resumed", []runtime.Object{pause(map[string]string{pausedKey: "false"})}, false, false},
+		{"invalid", []runtime.Object{pause(map[string]string{pausedKey: "maybe"})}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{
+				Clientset:      fake.NewSimpleClientset(tt.objects...),
+				PauseNamespace: "k8s-restarter",
+				PauseConfigMap: "pause",
+			}
+			got, err := c.IsPaused(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsPaused() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsPaused() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_SetPaused(t *testing.T) {
+	existing := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8s-restarter", Name: "pause", Labels: map[string]string{"app": "k8s-restarter"}},
+	}
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+	}{
+		{"create", nil},
+		{"update", []runtime.Object{existing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.objects...)
+			auditPath := filepath.Join(t.TempDir(), "audit.log")
+			auditLog, err := audit.New(auditPath, "identity")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Controller{
+				Logger:         zap.NewNop(),
+				Clientset:      clientset,
+				PauseNamespace: "k8s-restarter",
+				PauseConfigMap: "pause",
+				Audit:          auditLog,
+			}
+			for _, paused := range []bool{true, false, true} {
+				err := c.SetPaused(context.Background(), paused)
+				if err != nil {
+					t.Fatalf("SetPaused(%v) error = %v", paused, err)
+				}
+				got, err := c.IsPaused(context.Background())
+				if err != nil || got != paused {
+					t.Fatalf("IsPaused() = %v, %v after SetPaused(%v)", got, err, paused)
+				}
+			}
+			cm, err := clientset.CoreV1().ConfigMaps("k8s-restarter").Get(context.Background(), "pause", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.objects) > 0 && cm.Labels["app"] != "k8s-restarter" {
+				t.Errorf("SetPaused() dropped the labels of the configmap")
+			}
+
+			// The audit log records the written objects, not a patch
+			b, err := ioutil.ReadFile(auditPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("audit log has %v records, want 3", len(lines))
+			}
+			var record audit.Record
+			err = json.Unmarshal([]byte(lines[0]), &record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var written v1.ConfigMap
+			err = json.Unmarshal(record.Object, &written)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantReason := "update"
+			if len(tt.objects) == 0 {
+				wantReason = "create"
+			}
+			if record.Decision != audit.DecisionPause || record.Reason != wantReason || record.Patch != nil || written.Data[pausedKey] != "true" {
+				t.Errorf("audit record = %+v, object %v", record, string(record.Object))
+			}
+		})
+	}
+
+	c := &Controller{Logger: zap.NewNop(), Clientset: fake.NewSimpleClientset()}
+	if err := c.SetPaused(context.Background(), true); err == nil {
+		t.Errorf("SetPaused() without pause configmap succeeded")
+	}
+}
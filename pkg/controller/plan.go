@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"github.com/shaardie/k8s-restarter/pkg/server"
+)
+
+// Decisions of a plan
+const (
+	DecisionRestart = "restart"
+	DecisionSkip    = "skip"
+	DecisionIgnore  = "ignore"
+	DecisionError   = "error"
+)
+
+// PlannedApp is the decision the next reconcilation would take on an app
+type PlannedApp struct {
+	server.App
+	Decision string `json:"decision"`
+	// Rule is the matching rule, only set by Explain
+	Rule *config.Rule `json:"rule,omitempty"`
+}
+
+// Plan runs the selection, status and age checks and the strategy limits of
+// the reconcilation on all apps at the given time without changing anything. The apps are sorted by
+// namespace, kind and name.
+func (c *Controller) Plan(ctx context.Context, now time.Time) ([]PlannedApp, error) {
+	c.m.Lock()
+	started := c.namespaces != nil
+	c.m.Unlock()
+	if !started {
+		var err error
+		if c.Namespace != "" {
+			err = c.loadNamespace(ctx, c.Namespace)
+		} else {
+			err = c.startNamespaceInformer(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	apps, err := c.listApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paused, err := c.IsPaused(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]PlannedApp, 0, len(apps))
+	sortByLastRestart(apps)
+	limits := c.newRuleLimits(apps, now)
+	for _, a := range apps {
+		state, err := c.evaluate(a, now)
+		if err != nil {
+			state.Status = statusFailed
+			state.Error = err.Error()
+		}
+		p := decide(state, paused)
+		if p.Decision == DecisionRestart {
+			if status := limits.check(p.Matcher); status != "" {
+				p.Status = status
+				p.Decision = DecisionSkip
+			} else {
+				limits.add(p.Matcher)
+			}
+		}
+		plan = append(plan, p)
+	}
+	sort.Slice(plan, func(i, j int) bool {
+		a, b := plan[i], plan[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return plan, nil
+}
+
+// Explain evaluates a single app at the given time like Plan. The pause
+// state is not taken into account.
+func (c *Controller) Explain(ctx context.Context, namespace, kind, name string, now time.Time) (PlannedApp, error) {
+	app, err := c.getApp(ctx, namespace, kind, name)
+	if err != nil {
+		return PlannedApp{}, err
+	}
+	err = c.loadNamespace(ctx, namespace)
+	if err != nil {
+		return PlannedApp{}, err
+	}
+	state, err := c.evaluate(app, now)
+	if err != nil {
+		state.Status = statusFailed
+		state.Error = err.Error()
+	}
+	p := decide(state, false)
+	rule, ok, err := c.config().Match(c.target(app))
+	if err == nil && ok {
+		p.Rule = &rule
+	}
+	return p, nil
+}
+
+// decide returns the decision of the reconcilation on the evaluated state
+// of an app
+func decide(state server.App, paused bool) PlannedApp {
+	p := PlannedApp{App: state}
+	switch state.Status {
+	case statusDue:
+		if paused {
+			p.Status = statusDeferredPaused
+			p.Decision = DecisionSkip
+			return p
+		}
+		p.Decision = DecisionRestart
+	case statusExcluded:
+		p.Decision = DecisionIgnore
+	case statusFailed:
+		p.Decision = DecisionError
+	default:
+		p.Decision = DecisionSkip
+	}
+	return p
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_decide(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &Controller{Cfg: &config.Config{
+		RestartInterval: time.Hour,
+		Exclude: config.Matcher{
+			Enabled:   true,
+			Selectors: []config.Selector{{Namespace: "kube-system"}},
+		},
+	}}
+	deployment := func(namespace string, age time.Duration, ready bool) App {
+		d := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              "app",
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+		}}
+		d.Status.Replicas = 1
+		if ready {
+			d.Status.UpdatedReplicas = 1
+		}
+		return (*Deployment)(d)
+	}
+	statefulSet := func(ready bool) App {
+		s := &appv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              "db",
+			CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour)),
+		}}
+		s.Status.Replicas = 1
+		if ready {
+			s.Status.UpdatedReplicas = 1
+		}
+		return (*StatefulSet)(s)
+	}
+	tests := []struct {
+		name       string
+		app        App
+		paused     bool
+		wantStatus string
+		want       string
+	}{
+		{"due", deployment("default", 2*time.Hour, true), false, statusDue, DecisionRestart},
+		{"due but paused", deployment("default", 2*time.Hour, true), true, statusDeferredPaused, DecisionSkip},
+		{"scheduled", deployment("default", time.Minute, true), false, statusScheduled, DecisionSkip},
+		{"not ready", deployment("default", 2*time.Hour, false), false, statusNotReady, DecisionSkip},
+		{"statefulset due", statefulSet(true), false, statusDue, DecisionRestart},
+		{"statefulset not ready", statefulSet(false), false, statusNotReady, DecisionSkip},
+		{"excluded", deployment("kube-system", 2*time.Hour, true), false, statusExcluded, DecisionIgnore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := c.evaluate(tt.app, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := decide(state, tt.paused)
+			if got.Status != tt.wantStatus || got.Decision != tt.want {
+				t.Errorf("decide() = %v %v, want %v %v", got.Status, got.Decision, tt.wantStatus, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WaitForRollout polls the app in the interval until its rollout is complete
+// or the context is done
+func (c *Controller) WaitForRollout(ctx context.Context, app App, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for !app.RolloutComplete() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("rollout of %v %v/%v did not complete, %w", app.GetKind(), app.GetNamespace(), app.GetName(), ctx.Err())
+		case <-ticker.C:
+		}
+		var err error
+		app, err = c.getApp(ctx, app.GetNamespace(), app.GetKind(), app.GetName())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SimulatedRestart is a restart in a simulation
+type SimulatedRestart struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Rule      string    `json:"rule"`
+	// Due is when the restart was due, before Time if it was deferred, e.g.
+	// by a window or the strategy of the rule
+	Due time.Time `json:"due"`
+}
+
+// Simulate runs the reconcilation of the manifests with a virtual clock from
+// start over the horizon and returns the restarts in order. The apps of the
+// manifests are modified like by real restarts. Apps without creation time
+// are considered created at start. Namespaces of apps missing in the manifests
+// have no labels. The strategies of the rules are applied like by the
+// reconcilation, assuming that every rollout completes until the next
+// reconcilation. The pause state is not modelled.
+func Simulate(cfg *config.Config, manifests *Manifests, start time.Time, horizon time.Duration) ([]SimulatedRestart, error) {
+	if cfg.ReconcilationInterval <= 0 {
+		return nil, fmt.Errorf("invalid reconcilation interval %v", cfg.ReconcilationInterval)
+	}
+	namespaces, err := staticNamespaceLister(withAppNamespaces(manifests))
+	if err != nil {
+		return nil, err
+	}
+	c := &Controller{Cfg: cfg, namespaces: namespaces}
+	for _, app := range manifests.Apps {
+		if app.GetCreationTimestamp().Time.IsZero() {
+			app.SetCreationTimestamp(metav1.NewTime(start))
+		}
+	}
+
+	var restarts []SimulatedRestart
+	apps := append([]App{}, manifests.Apps...)
+	end := start.Add(horizon)
+	for now := start; !now.After(end); now = now.Add(cfg.ReconcilationInterval) {
+		sortByLastRestart(apps)
+		limits := c.newRuleLimits(apps, now)
+		// Only the rollouts of the manifests are in progress at the start
+		if now.After(start) {
+			limits.rolling = make(map[string]int)
+		}
+		for _, app := range apps {
+			state, err := c.evaluate(app, now)
+			if err != nil {
+				return nil, err
+			}
+			if state.Status != statusDue || limits.check(state.Matcher) != "" {
+				continue
+			}
+			limits.add(state.Matcher)
+			restarts = append(restarts, SimulatedRestart{
+				Time:      now,
+				Namespace: app.GetNamespace(),
+				Kind:      app.GetKind(),
+				Name:      app.GetName(),
+				Rule:      state.Matcher,
+				Due:       *state.NextRestart,
+			})
+			pts := app.GetPodTemplateSpec()
+			if pts.Annotations == nil {
+				pts.Annotations = make(map[string]string)
+			}
+			pts.Annotations[restartedAtAnnotation] = now.Format(time.RFC3339)
+		}
+	}
+	return restarts, nil
+}
+
+// withAppNamespaces returns the Namespaces of the manifests and a Namespace
+// without labels for every other namespace of the apps
+func withAppNamespaces(manifests *Manifests) []*v1.Namespace {
+	namespaces := append([]*v1.Namespace{}, manifests.Namespaces...)
+	known := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		known[ns.Name] = true
+	}
+	for _, app := range manifests.Apps {
+		if known[app.GetNamespace()] {
+			continue
+		}
+		known[app.GetNamespace()] = true
+		namespaces = append(namespaces, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: app.GetNamespace()}})
+	}
+	return namespaces
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+)
+
+const testManifests = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+  labels:
+    tier: prod
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  namespace: prod
+  name: web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: prod
+  name: web
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: ignored
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: StatefulSet
+  metadata:
+    namespace: prod
+    name: db
+  status:
+    replicas: 1
+    updatedReplicas: 1
+    readyReplicas: 1
+    availableReplicas: 1
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    namespace: dev
+    name: web
+`
+
+func TestSimulate(t *testing.T) {
+	cfg, err := config.ParseConfig([]byte(`
+reconcilationInterval: 1h
+restartInterval: 24h
+rules:
+- name: prod
+  selector:
+    namespaceSelector:
+      matchLabels:
+        tier: prod
+  window:
+    start: "02:00"
+    end: "04:00"
+- name: rest
+  action: ignore
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manifests{}
+	err = m.read(strings.NewReader(testManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Apps) != 3 || len(m.Namespaces) != 1 {
+		t.Fatalf("read %v apps and %v namespaces, want 3 and 1", len(m.Apps), len(m.Namespaces))
+	}
+
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	restarts, err := Simulate(cfg, m, start, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range restarts {
+		got = append(got, r.Time.Format("01-02T15")+" "+r.Kind+" "+r.Namespace+"/"+r.Name+" "+r.Rule)
+	}
+	want := []string{
+		"06-02T02 Deployment prod/web prod",
+		"06-02T02 StatefulSet prod/db prod",
+		"06-03T02 Deployment prod/web prod",
+		"06-03T02 StatefulSet prod/db prod",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Simulate() = %v, want %v", got, want)
+	}
+	if !restarts[0].Due.Equal(start.Add(24 * time.Hour)) {
+		t.Errorf("Due = %v, want %v", restarts[0].Due, start.Add(24*time.Hour))
+	}
+}
+
+func TestSimulate_strategy(t *testing.T) {
+	const manifests = `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    namespace: default
+    name: a
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    namespace: default
+    name: b
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    namespace: default
+    name: c
+`
+	tests := []struct {
+		name     string
+		strategy string
+		want     []string
+	}{
+		{
+			name: "unlimited",
+			want: []string{"24 a", "24 b", "24 c", "48 a", "48 b", "48 c", "72 a", "72 b", "72 c"},
+		},
+		{
+			name:     "max concurrent",
+			strategy: "{maxConcurrent: 2}",
+			want:     []string{"24 a", "24 b", "25 c", "48 a", "48 b", "49 c", "72 a", "72 b"},
+		},
+		{
+			// The apps waiting the longest go first, so that none starves
+			name:     "budget",
+			strategy: "{maxRestarts: 2, budgetPeriod: 24h}",
+			want:     []string{"24 a", "24 b", "48 c", "48 a", "72 b", "72 c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "reconcilationInterval: 1h\nrestartInterval: 24h\nrules:\n- name: all\n"
+			if tt.strategy != "" {
+				content += "  strategy: " + tt.strategy + "\n"
+			}
+			cfg, err := config.ParseConfig([]byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := &Manifests{}
+			err = m.read(strings.NewReader(manifests))
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+			restarts, err := Simulate(cfg, m, start, 72*time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range restarts {
+				got = append(got, fmt.Sprintf("%v %v", r.Time.Sub(start).Hours(), r.Name))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Simulate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
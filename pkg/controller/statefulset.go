@@ -7,6 +7,7 @@ import (
 	appv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -18,18 +19,34 @@ func (*StatefulSet) GetKind() string {
 }
 
 func (s *StatefulSet) StatusOK() bool {
-	return s.Status.Replicas != s.Status.UpdatedReplicas ||
-		s.Status.ReadyReplicas != s.Status.AvailableReplicas
+	return s.Status.Replicas == s.Status.UpdatedReplicas &&
+		s.Status.ReadyReplicas == s.Status.AvailableReplicas
+}
+
+func (s *StatefulSet) RolloutComplete() bool {
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	return s.Status.ObservedGeneration >= s.Generation &&
+		s.Status.UpdatedReplicas == replicas &&
+		s.Status.ReadyReplicas == replicas &&
+		s.Status.CurrentRevision == s.Status.UpdateRevision
 }
 
 func (s *StatefulSet) GetPodTemplateSpec() *v1.PodTemplateSpec {
 	return &s.Spec.Template
 }
 
-func (s *StatefulSet) Update(ctx context.Context, clientset *kubernetes.Clientset) error {
-	_, err := clientset.AppsV1().StatefulSets(s.Namespace).Update(context.TODO(), (*appv1.StatefulSet)(s), metav1.UpdateOptions{})
+func (s *StatefulSet) Patch(ctx context.Context, clientset kubernetes.Interface, patch []byte) (err error) {
+	ctx, span := startAppSpan(ctx, "StatefulSet.Patch", s)
+	defer func() {
+		endSpan(span, err)
+	}()
+	result, err := clientset.AppsV1().StatefulSets(s.Namespace).Patch(ctx, s.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to update %v %v/%v, %v", s.GetKind(), s.GetNamespace(), s.GetName(), err)
+		return fmt.Errorf("failed to patch %v %v/%v, %w", s.GetKind(), s.GetNamespace(), s.GetName(), err)
 	}
+	*s = StatefulSet(*result)
 	return nil
 }
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+)
+
+// ruleLimits tracks the limits of the rule strategies during a reconcilation
+type ruleLimits struct {
+	// strategies, apps rolling out and restarts within the budget period by
+	// rule name
+	strategies map[string]*config.Strategy
+	rolling    map[string]int
+	restarts   map[string]int
+}
+
+// newRuleLimits counts the apps rolling out and the apps restarted within the
+// budget period for every rule with a strategy
+func (c *Controller) newRuleLimits(apps []App, now time.Time) *ruleLimits {
+	l := &ruleLimits{
+		strategies: make(map[string]*config.Strategy),
+		rolling:    make(map[string]int),
+		restarts:   make(map[string]int),
+	}
+	cfg := c.config()
+	for _, app := range apps {
+		rule, ok, err := cfg.Match(c.target(app))
+		if err != nil || !ok || rule.Action != config.ActionRestart || rule.Strategy == nil {
+			continue
+		}
+		if _, ok := l.strategies[rule.Name]; !ok {
+			l.strategies[rule.Name] = rule.Strategy
+		}
+		if !app.RolloutComplete() {
+			l.rolling[rule.Name]++
+		}
+		last, err := getTimePodTemplateSpec(app.GetPodTemplateSpec())
+		if err == nil && last != nil && now.Sub(*last) < rule.Strategy.BudgetPeriod {
+			l.restarts[rule.Name]++
+		}
+	}
+	return l
+}
+
+// check returns the status deferring the restart of an app of the rule, or an
+// empty string, if the strategy allows the restart
+func (l *ruleLimits) check(rule string) string {
+	s, ok := l.strategies[rule]
+	if !ok {
+		return ""
+	}
+	if s.MaxConcurrent > 0 && l.rolling[rule] >= s.MaxConcurrent {
+		return statusDeferredConcurrency
+	}
+	if s.MaxRestarts > 0 && l.restarts[rule] >= s.MaxRestarts {
+		return statusDeferredBudget
+	}
+	return ""
+}
+
+// add counts the restart of an app of the rule
+func (l *ruleLimits) add(rule string) {
+	l.rolling[rule]++
+	l.restarts[rule]++
+}
+
+// sortByLastRestart sorts the apps by their last restart or creation, so that
+// the apps waiting the longest are restarted first, if the strategies limit
+// the restarts
+func sortByLastRestart(apps []App) {
+	last := func(app App) time.Time {
+		t, err := getTimePodTemplateSpec(app.GetPodTemplateSpec())
+		if err != nil || t == nil {
+			return app.GetCreationTimestamp().Time
+		}
+		return *t
+	}
+	sort.SliceStable(apps, func(i, j int) bool {
+		return last(apps[i]).Before(last(apps[j]))
+	})
+}
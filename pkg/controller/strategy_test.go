@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"go.uber.org/zap"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestController_reconcileStrategy(t *testing.T) {
+	deployment := func(name string, restarted time.Duration, rolling bool) *appv1.Deployment {
+		d := &appv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              name,
+			Generation:        1,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-48 * time.Hour)),
+		}}
+		if restarted > 0 {
+			d.Spec.Template.Annotations = map[string]string{
+				restartedAtAnnotation: time.Now().Add(-restarted).Format(time.RFC3339),
+			}
+		}
+		d.Status.ObservedGeneration = 1
+		d.Status.Replicas = 1
+		d.Status.UpdatedReplicas = 1
+		d.Status.ReadyReplicas = 1
+		d.Status.AvailableReplicas = 1
+		if rolling {
+			d.Generation = 2
+		}
+		return d
+	}
+	tests := []struct {
+		name     string
+		strategy *config.Strategy
+		want     map[string]int
+	}{
+		{"unlimited", nil, map[string]int{statusRestarted: 3, statusScheduled: 1}},
+		{
+			"max concurrent",
+			&config.Strategy{MaxConcurrent: 2},
+			map[string]int{statusRestarted: 1, statusDeferredConcurrency: 2, statusScheduled: 1},
+		},
+		{
+			"budget",
+			&config.Strategy{MaxRestarts: 2, BudgetPeriod: 24 * time.Hour},
+			map[string]int{statusRestarted: 1, statusDeferredBudget: 2, statusScheduled: 1},
+		},
+		{
+			"budget period over",
+			&config.Strategy{MaxRestarts: 2, BudgetPeriod: 5 * time.Minute},
+			map[string]int{statusRestarted: 2, statusDeferredBudget: 1, statusScheduled: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				deployment("a", 0, false),
+				deployment("b", 0, false),
+				deployment("c", 0, false),
+				// Restarted recently and still rolling out
+				deployment("rolling", 10*time.Minute, true),
+			)
+			c := &Controller{
+				Cfg: &config.Config{
+					RestartInterval: 24 * time.Hour,
+					Rules:           []config.Rule{{Name: "all", Strategy: tt.strategy}},
+				},
+				Clientset: clientset,
+				Logger:    zap.NewNop(),
+			}
+			result, err := c.reconcile(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]int)
+			for _, state := range result.states {
+				got[state.Status]++
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("reconcile() status = %v, want %v", got, tt.want)
+			}
+			for status, n := range tt.want {
+				if got[status] != n {
+					t.Errorf("reconcile() status = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/shaardie/k8s-restarter/pkg/controller")
+
+// startAppSpan starts a span with the app as attributes
+func startAppSpan(ctx context.Context, name string, app App) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("app.namespace", app.GetNamespace()),
+		attribute.String("app.kind", app.GetKind()),
+		attribute.String("app.name", app.GetName()),
+	))
+}
+
+// endSpan records the error, if any, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+)
+
+const (
+	cloudEventsSpecVersion   = "1.0"
+	cloudEventsContentType   = "application/cloudevents+json"
+	defaultCloudEventsSource = "/k8s-restarter"
+)
+
+// cloudEventTypes maps the event types to the CloudEvents types
+var cloudEventTypes = map[string]string{
+	EventStarted:        "io.k8s-restarter.app.restarting",
+	EventSucceeded:      "io.k8s-restarter.app.restarted",
+	EventFailed:         "io.k8s-restarter.app.restart-failed",
+	EventSkippedOverdue: "io.k8s-restarter.app.skipped-overdue",
+	EventPostponed:      "io.k8s-restarter.app.postponed",
+}
+
+// cloudEvent is a CloudEvent in the structured JSON mode
+type cloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	Data            Event  `json:"data"`
+}
+
+// cloudEvents is a target delivering events as CloudEvents in the structured
+// JSON mode via HTTP POST
+type cloudEvents struct {
+	sink   string
+	host   string
+	source string
+	events []string
+	client *http.Client
+}
+
+// newCloudEvents returns a new CloudEvents target from its configuration
+func newCloudEvents(cfg config.CloudEvents) (*cloudEvents, error) {
+	u, err := url.Parse(cfg.Sink)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sink %v, %w", cfg.Sink, err)
+	}
+	c := &cloudEvents{
+		sink:   cfg.Sink,
+		host:   u.Host,
+		source: cfg.Source,
+		events: cfg.Events,
+		client: &http.Client{},
+	}
+	if c.source == "" {
+		c.source = defaultCloudEventsSource
+	}
+	return c, nil
+}
+
+func (c *cloudEvents) String() string {
+	return "cloudevents " + c.host
+}
+
+func (c *cloudEvents) accepts(e Event) bool {
+	return acceptsEvent(c.events, e)
+}
+
+func (c *cloudEvents) deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(c.cloudEvent(e))
+	if err != nil {
+		return fmt.Errorf("failed to marshal cloud event, %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.sink, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request, %w", err)
+	}
+	req.Header.Set("Content-Type", cloudEventsContentType)
+	return post(c.client, req)
+}
+
+// cloudEvent converts an event into a CloudEvent
+func (c *cloudEvents) cloudEvent(e Event) cloudEvent {
+	t, ok := cloudEventTypes[e.Type]
+	if !ok {
+		t = "io.k8s-restarter.app." + e.Type
+	}
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              e.ID,
+		Source:          c.source,
+		Type:            t,
+		Subject:         strings.Join([]string{e.Namespace, e.Kind, e.Name}, "/"),
+		Time:            e.Time.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            e,
+	}
+}
@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Types of events
+const (
+	EventStarted        = "started"
+	EventSucceeded      = "succeeded"
+	EventFailed         = "failed"
+	EventSkippedOverdue = "skipped-overdue"
+	EventPostponed      = "postponed"
+)
+
+const (
+	queueSize         = 100
+	defaultMaxRetries = 5
+	minBackoff        = time.Second
+	maxBackoff        = time.Minute
+	deliveryTimeout   = 10 * time.Second
+)
+
+var (
+	opsDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_restarter_notification_deliveries_total",
+		Help: "The total number of notification deliveries by target and result",
+	}, []string{"target", "result"})
+)
+
+// Event is a restart lifecycle event of an app
+type Event struct {
+	// ID identifies the event across all retries of its delivery
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Trigger   string    `json:"trigger,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// target is a destination of notifications
+type target interface {
+	// String returns a description of the target used in logs and metrics
+	String() string
+	// accepts returns, if the event should be delivered to the target
+	accepts(Event) bool
+	// deliver delivers a single event
+	deliver(context.Context, Event) error
+}
+
+// queue holds the events to deliver to a single target
+type queue struct {
+	target
+	events     chan Event
+	maxRetries int
+}
+
+// Notifier delivers events asynchronously to the configured targets, so that
+// the reconcilation is not blocked. A nil Notifier discards all events.
+type Notifier struct {
+	logger *zap.Logger
+	queues []*queue
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+	m      *sync.RWMutex
+	closed bool
+}
+
+// New returns a new Notifier for the configured targets and starts the
+// delivery
+func New(logger *zap.Logger, cfg config.Notifications) (*Notifier, error) {
+	queues, err := newQueues(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+		wg:     &sync.WaitGroup{},
+		m:      &sync.RWMutex{},
+	}
+	n.start(queues)
+	return n, nil
+}
+
+// Targets are the targets of a configuration prepared by Prepare
+type Targets struct {
+	queues []*queue
+}
+
+// Prepare creates the targets of a configuration without starting the
+// delivery, so that the configuration can be checked before it is used
+func Prepare(cfg config.Notifications) (*Targets, error) {
+	queues, err := newQueues(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Targets{queues: queues}, nil
+}
+
+// Update replaces the targets with the newly configured ones. The events
+// already queued for the old targets are still delivered.
+func (n *Notifier) Update(cfg config.Notifications) error {
+	targets, err := Prepare(cfg)
+	if err != nil {
+		return err
+	}
+	return n.Use(targets)
+}
+
+// Use replaces the targets with the prepared ones. The events already queued
+// for the old targets are still delivered.
+func (n *Notifier) Use(targets *Targets) error {
+	if n == nil {
+		return nil
+	}
+	n.m.Lock()
+	defer n.m.Unlock()
+	if n.closed {
+		return fmt.Errorf("notifier is shut down")
+	}
+	for _, q := range n.queues {
+		close(q.events)
+	}
+	n.start(targets.queues)
+	return nil
+}
+
+// start starts the delivery of the queues. Must be called with the lock held
+// or before the Notifier is used.
+func (n *Notifier) start(queues []*queue) {
+	n.queues = queues
+	for _, q := range n.queues {
+		n.wg.Add(1)
+		go n.run(n.ctx, q)
+	}
+}
+
+// newQueues returns the queues for the configured targets
+func newQueues(cfg config.Notifications) ([]*queue, error) {
+	queues := []*queue{}
+	for i, w := range cfg.Webhooks {
+		t, err := newWebhook(w)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create webhook %v, %w", i, err)
+		}
+		queues = append(queues, newQueue(t, w.MaxRetries))
+	}
+	for i, ce := range cfg.CloudEvents {
+		t, err := newCloudEvents(ce)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cloud events sink %v, %w", i, err)
+		}
+		queues = append(queues, newQueue(t, ce.MaxRetries))
+	}
+	return queues, nil
+}
+
+// newQueue returns a new queue for a target. maxRetries defaults to
+// defaultMaxRetries, if nil.
+func newQueue(t target, maxRetries *int) *queue {
+	q := &queue{
+		target:     t,
+		events:     make(chan Event, queueSize),
+		maxRetries: defaultMaxRetries,
+	}
+	if maxRetries != nil {
+		q.maxRetries = *maxRetries
+	}
+	return q
+}
+
+// Notify queues an event for all targets accepting it. If the queue of a
+// target is full, the event is dropped for this target.
+func (n *Notifier) Notify(e Event) {
+	if n == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	n.m.RLock()
+	defer n.m.RUnlock()
+	if n.closed {
+		return
+	}
+	for _, q := range n.queues {
+		if !q.accepts(e) {
+			continue
+		}
+		select {
+		case q.events <- e:
+		default:
+			opsDeliveries.WithLabelValues(q.String(), "dropped").Inc()
+			n.logger.Sugar().Warnw("Notification queue full, dropping event", "target", q.String(), "event", e)
+		}
+	}
+}
+
+// Shutdown stops accepting events and waits for the queued events to be
+// delivered until the context is done
+func (n *Notifier) Shutdown(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	n.m.Lock()
+	if !n.closed {
+		n.closed = true
+		for _, q := range n.queues {
+			close(q.events)
+		}
+	}
+	n.m.Unlock()
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		n.cancel()
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		return fmt.Errorf("failed to deliver all notifications, %w", ctx.Err())
+	}
+}
+
+// run delivers the events of a queue
+func (n *Notifier) run(ctx context.Context, q *queue) {
+	defer n.wg.Done()
+	for e := range q.events {
+		err := n.deliver(ctx, q, e)
+		if err != nil {
+			opsDeliveries.WithLabelValues(q.String(), "failed").Inc()
+			n.logger.Sugar().Errorw("Failed to deliver notification", "target", q.String(), "event", e, "error", err)
+			continue
+		}
+		opsDeliveries.WithLabelValues(q.String(), "delivered").Inc()
+	}
+}
+
+// deliver delivers a single event and retries with exponential backoff
+func (n *Notifier) deliver(ctx context.Context, q *queue, e Event) error {
+	backoff := minBackoff
+	var err error
+	for i := 0; i <= q.maxRetries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		dctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		err = q.deliver(dctx, e)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		n.logger.Sugar().Debugw("Failed to deliver notification, retrying", "target", q.String(), "attempt", i+1, "error", err)
+	}
+	return err
+}
+
+// acceptsEvent returns, if the event type is in events. An empty list accepts
+// all events.
+func acceptsEvent(events []string, e Event) bool {
+	if len(events) == 0 {
+		return true
+	}
+	for _, t := range events {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+	"go.uber.org/zap"
+)
+
+func TestNotifier_Webhook(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	err := ioutil.WriteFile(secretFile, []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type request struct {
+		body      string
+		signature string
+	}
+	requests := make(chan request, 10)
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		// Fail the first attempt to test the retry
+		if attempts == 1 {
+			http.Error(w, "failed", http.StatusInternalServerError)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		requests <- request{body: string(b), signature: r.Header.Get(SignatureHeader)}
+	}))
+	defer ts.Close()
+
+	n, err := New(zap.NewNop(), config.Notifications{
+		Webhooks: []config.Webhook{{
+			URL:        ts.URL,
+			SecretFile: secretFile,
+			Template:   "{{ .Type }} {{ .Namespace }}/{{ .Name }}",
+			Events:     []string{EventSucceeded},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify(Event{Type: EventStarted, Namespace: "namespace", Name: "name"})
+	n.Notify(Event{Type: EventSucceeded, Namespace: "namespace", Name: "name"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = n.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(requests)
+
+	got := []request{}
+	for r := range requests {
+		got = append(got, r)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %v requests, want 1", len(got))
+	}
+	wantBody := "succeeded namespace/name"
+	if got[0].body != wantBody {
+		t.Errorf("body = %q, want %q", got[0].body, wantBody)
+	}
+	wantSignature := "sha256=" + sign([]byte("secret"), []byte(wantBody))
+	if got[0].signature != wantSignature {
+		t.Errorf("signature = %q, want %q", got[0].signature, wantSignature)
+	}
+}
+
+func TestNotifier_CloudEvents(t *testing.T) {
+	type request struct {
+		contentType string
+		event       map[string]interface{}
+	}
+	requests := make(chan request, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := map[string]interface{}{}
+		err := json.NewDecoder(r.Body).Decode(&e)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests <- request{contentType: r.Header.Get("Content-Type"), event: e}
+	}))
+	defer ts.Close()
+
+	n, err := New(zap.NewNop(), config.Notifications{
+		CloudEvents: []config.CloudEvents{{Sink: ts.URL}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify(Event{Type: EventSucceeded, Namespace: "namespace", Kind: "Deployment", Name: "name"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = n.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(requests)
+
+	r, ok := <-requests
+	if !ok {
+		t.Fatal("got no request")
+	}
+	if r.contentType != cloudEventsContentType {
+		t.Errorf("content type = %q, want %q", r.contentType, cloudEventsContentType)
+	}
+	for k, want := range map[string]string{
+		"specversion": "1.0",
+		"type":        "io.k8s-restarter.app.restarted",
+		"source":      defaultCloudEventsSource,
+		"subject":     "namespace/Deployment/name",
+	} {
+		if got := r.event[k]; got != want {
+			t.Errorf("%v = %v, want %v", k, got, want)
+		}
+	}
+	if id, _ := r.event["id"].(string); id == "" {
+		t.Errorf("id is empty")
+	}
+}
+
+func TestNotifier_MaxRetries(t *testing.T) {
+	zero, one := 0, 1
+	tests := []struct {
+		name       string
+		maxRetries *int
+		want       int
+	}{
+		{"no retries", &zero, 1},
+		{"one retry", &one, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m sync.Mutex
+			attempts := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				m.Lock()
+				attempts++
+				m.Unlock()
+				http.Error(w, "failed", http.StatusInternalServerError)
+			}))
+			defer ts.Close()
+
+			n, err := New(zap.NewNop(), config.Notifications{
+				Webhooks: []config.Webhook{{URL: ts.URL, MaxRetries: tt.maxRetries}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			n.Notify(Event{Type: EventSucceeded, Namespace: "namespace", Name: "name"})
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err = n.Shutdown(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Lock()
+			defer m.Unlock()
+			if attempts != tt.want {
+				t.Errorf("attempts = %v, want %v", attempts, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifier_CloudEventsRetry(t *testing.T) {
+	var m sync.Mutex
+	ids := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := map[string]interface{}{}
+		err := json.NewDecoder(r.Body).Decode(&e)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m.Lock()
+		defer m.Unlock()
+		id, _ := e["id"].(string)
+		ids = append(ids, id)
+		// Fail the first attempt to test the retry
+		if len(ids) == 1 {
+			http.Error(w, "failed", http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	n, err := New(zap.NewNop(), config.Notifications{
+		CloudEvents: []config.CloudEvents{{Sink: ts.URL}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify(Event{Type: EventSucceeded, Namespace: "namespace", Kind: "Deployment", Name: "name"})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = n.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if len(ids) != 2 {
+		t.Fatalf("got %v attempts, want 2", len(ids))
+	}
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("ids = %v, want the same id for every attempt", ids)
+	}
+}
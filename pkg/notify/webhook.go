@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+)
+
+// SignatureHeader is the header containing the HMAC-SHA256 signature of the
+// payload in the form sha256=<hex>
+const SignatureHeader = "X-K8s-Restarter-Signature"
+
+// webhook is a target delivering events via HTTP POST
+type webhook struct {
+	url         string
+	host        string
+	secret      []byte
+	template    *template.Template
+	contentType string
+	events      []string
+	client      *http.Client
+}
+
+// newWebhook returns a new webhook target from its configuration
+func newWebhook(cfg config.Webhook) (*webhook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url %v, %w", cfg.URL, err)
+	}
+	w := &webhook{
+		url:         cfg.URL,
+		host:        u.Host,
+		contentType: cfg.ContentType,
+		events:      cfg.Events,
+		client:      &http.Client{},
+	}
+	if w.contentType == "" {
+		w.contentType = "application/json"
+	}
+	if cfg.SecretFile != "" {
+		secret, err := ioutil.ReadFile(cfg.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret file %v, %w", cfg.SecretFile, err)
+		}
+		w.secret = bytes.TrimSpace(secret)
+	}
+	if cfg.Template != "" {
+		w.template, err = template.New(u.Host).Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template, %w", err)
+		}
+	}
+	return w, nil
+}
+
+func (w *webhook) String() string {
+	return "webhook " + w.host
+}
+
+func (w *webhook) accepts(e Event) bool {
+	return acceptsEvent(w.events, e)
+}
+
+func (w *webhook) deliver(ctx context.Context, e Event) error {
+	body, err := w.payload(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request, %w", err)
+	}
+	req.Header.Set("Content-Type", w.contentType)
+	if len(w.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+sign(w.secret, body))
+	}
+	return post(w.client, req)
+}
+
+// payload renders the payload of an event
+func (w *webhook) payload(e Event) ([]byte, error) {
+	if w.template == nil {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal event, %w", err)
+		}
+		return b, nil
+	}
+	buf := &bytes.Buffer{}
+	err := w.template.Execute(buf, e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute template, %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sign returns the hex encoded HMAC-SHA256 of the body
+func sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// post sends the request and checks for a successful status code
+func post(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %v, %v", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
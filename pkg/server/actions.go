@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/audit"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	actionTimeout = 30 * time.Second
+	// imageActionTimeout is longer, since many apps can be restarted
+	imageActionTimeout = 5 * time.Minute
+)
+
+// ErrRejected is returned by Actions, if the action is rejected by the safety
+// checks of the controller
+var ErrRejected = errors.New("rejected")
+
+// Actions are the mutating actions on apps offered by the API
+type Actions interface {
+	// Restart restarts an app ahead of schedule, if it passes the safety
+	// checks
+	Restart(ctx context.Context, namespace, kind, name string) error
+
+	// Postpone postpones the next restart of an app until the given time
+	Postpone(ctx context.Context, namespace, kind, name string, until time.Time) error
+
+	// SetPaused pauses or resumes all automatic restarts
+	SetPaused(ctx context.Context, paused bool) error
+
+	// RestartImage restarts all apps running an image matching the pattern
+	RestartImage(ctx context.Context, image string) ([]ImageRestart, error)
+}
+
+// ImageRestart is the result of restarting a single app running an image
+type ImageRestart struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Image     string `json:"image"`
+	// Result is either restarted, skipped or failed
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SetActions sets the actions used by the API
+func (s *Server) SetActions(actions Actions) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.actions = actions
+}
+
+func (s *Server) getActions() Actions {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.actions
+}
+
+// AppActionHandler handles requests of the form
+// /api/v1/apps/{namespace}/{kind}/{name}/{action}
+func (s *Server) AppActionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/apps/"), "/")
+	if len(parts) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+	namespace, kind, name, action := parts[0], parts[1], parts[2], parts[3]
+
+	var until time.Time
+	switch action {
+	case "restart":
+	case "postpone":
+		var err error
+		until, err = parseUntil(r.URL.Query().Get("until"), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	user, status, err := s.authorize(r, "create", action, namespace, name)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	actions := s.getActions()
+	if actions == nil {
+		http.Error(w, "not leading", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(audit.WithUser(r.Context(), user), actionTimeout)
+	defer cancel()
+	if action == "restart" {
+		err = actions.Restart(ctx, namespace, kind, name)
+	} else {
+		err = actions.Postpone(ctx, namespace, kind, name, until)
+	}
+	writeActionResult(w, r, err)
+}
+
+// ImageRestartHandler handles requests restarting all apps running an image
+// given by the parameter image
+func (s *Server) ImageRestartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	image := r.URL.Query().Get("image")
+	if image == "" {
+		http.Error(w, "missing parameter image", http.StatusBadRequest)
+		return
+	}
+
+	user, status, err := s.authorize(r, "create", "restartimage", "", "")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	actions := s.getActions()
+	if actions == nil {
+		http.Error(w, "not leading", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(audit.WithUser(r.Context(), user), imageActionTimeout)
+	defer cancel()
+	results, err := actions.RestartImage(ctx, image)
+	if err != nil {
+		writeActionResult(w, r, err)
+		return
+	}
+	writeJSON(w, results)
+}
+
+// writeActionResult writes the result of an action as response
+func writeActionResult(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case err == nil:
+		fmt.Fprintf(w, "ok")
+	case errors.Is(err, ErrRejected):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case apierrors.IsNotFound(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseUntil parses the until parameter, which is either a RFC3339 time or a
+// duration relative to now
+func parseUntil(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("missing parameter until")
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parameter until is neither a RFC3339 time nor a duration, %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseUntil(t *testing.T) {
+	now := time.Date(2022, 6, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "Duration",
+			s:    "2h",
+			want: now.Add(2 * time.Hour),
+		},
+		{
+			name: "RFC3339",
+			s:    "2022-06-17T08:00:00Z",
+			want: time.Date(2022, 6, 17, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Empty",
+			s:       "",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid",
+			s:       "tomorrow",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUntil(tt.s, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseUntil() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseUntil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+)
+
+// App represents the state of a single app as seen by the controller in the
+// last reconcilation
+type App struct {
+	Namespace   string           `json:"namespace"`
+	Kind        string           `json:"kind"`
+	Name        string           `json:"name"`
+	Selected    bool             `json:"selected"`
+	Matcher     string           `json:"matcher,omitempty"`
+	Selector    *config.Selector `json:"selector,omitempty"`
+	LastRestart *time.Time       `json:"lastRestart,omitempty"`
+	NextRestart *time.Time       `json:"nextRestart,omitempty"`
+	Status      string           `json:"status"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// SetApps replaces the known apps
+func (s *Server) SetApps(apps []App) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.apps = apps
+}
+
+// GetApps returns the known apps filtered by namespace and kind. The kind is
+// matched case-insensitively like by ParseAppRef. Empty filters match
+// everything.
+func (s *Server) GetApps(namespace, kind string) []App {
+	s.m.Lock()
+	defer s.m.Unlock()
+	apps := make([]App, 0, len(s.apps))
+	for _, a := range s.apps {
+		if namespace != "" && a.Namespace != namespace {
+			continue
+		}
+		if kind != "" && !strings.EqualFold(a.Kind, kind) {
+			continue
+		}
+		apps = append(apps, a)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Namespace != apps[j].Namespace {
+			return apps[i].Namespace < apps[j].Namespace
+		}
+		if apps[i].Kind != apps[j].Kind {
+			return apps[i].Kind < apps[j].Kind
+		}
+		return apps[i].Name < apps[j].Name
+	})
+	return apps
+}
+
+// AppsHandler handles requests listing the known apps
+func (s *Server) AppsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	writeJSON(w, s.GetApps(q.Get("namespace"), q.Get("kind")))
+}
+
+// writeJSON writes v as JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestServer_GetApps(t *testing.T) {
+	s := &Server{m: &sync.Mutex{}}
+	s.SetApps([]App{
+		{Namespace: "shop", Kind: "StatefulSet", Name: "db"},
+		{Namespace: "shop", Kind: "Deployment", Name: "web"},
+		{Namespace: "default", Kind: "Deployment", Name: "api"},
+	})
+	tests := []struct {
+		name      string
+		namespace string
+		kind      string
+		want      []string
+	}{
+		{"all", "", "", []string{"api", "web", "db"}},
+		{"namespace", "shop", "", []string{"web", "db"}},
+		{"kind", "", "Deployment", []string{"api", "web"}},
+		{"kind in lower case", "", "deployment", []string{"api", "web"}},
+		{"namespace and kind", "shop", "STATEFULSET", []string{"db"}},
+		{"unknown kind", "", "DaemonSet", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apps := s.GetApps(tt.namespace, tt.kind)
+			got := make([]string, 0, len(apps))
+			for _, a := range apps {
+				got = append(got, a.Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetApps() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("GetApps() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIGroup is the group of the virtual resource used for the authorization
+	// of the API
+	APIGroup = "k8s-restarter.haardiek.org"
+	// APIResource is the virtual resource used for the authorization of the
+	// API
+	APIResource = "apps"
+
+	authTimeout = 10 * time.Second
+)
+
+// authorize authenticates the bearer token of the request using a
+// TokenReview and authorizes the user for the verb on the subresource of the
+// virtual resource using a SubjectAccessReview. It returns the name of the
+// user and on failure the status code which should be send to the client.
+func (s *Server) authorize(r *http.Request, verb, subresource, namespace, name string) (string, int, error) {
+	if s.clientset == nil {
+		return "", http.StatusServiceUnavailable, fmt.Errorf("authorization not available")
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		return "", http.StatusUnauthorized, fmt.Errorf("missing bearer token")
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), authTimeout)
+	defer cancel()
+
+	tr, err := s.clientset.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("failed to review token, %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return "", http.StatusUnauthorized, fmt.Errorf("not authenticated, %v", tr.Status.Error)
+	}
+
+	user := tr.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar, err := s.clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        verb,
+				Group:       APIGroup,
+				Resource:    APIResource,
+				Subresource: subresource,
+				Name:        name,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("failed to review access, %w", err)
+	}
+	if !sar.Status.Allowed {
+		return "", http.StatusForbidden, fmt.Errorf("user %v is not allowed to %v %v/%v", user.Username, verb, APIResource, subresource)
+	}
+
+	s.logger.Sugar().Infow("authorized request",
+		"user", user.Username,
+		"verb", verb,
+		"subresource", subresource,
+		"namespace", namespace,
+		"name", name,
+	)
+	return user.Username, http.StatusOK, nil
+}
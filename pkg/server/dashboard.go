@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"time": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	},
+}).Parse(dashboardHTML))
+
+// dashboard holds the data rendered by the dashboard
+type dashboard struct {
+	Status   Status
+	Config   string
+	Apps     []App
+	Restarts []Restart
+}
+
+// DashboardHandler handles requests for the read-only dashboard
+func (s *Server) DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	d := dashboard{
+		Status:   s.GetStatus(),
+		Apps:     s.GetApps(q.Get("namespace"), q.Get("kind")),
+		Restarts: s.GetRestarts(),
+	}
+	cfg, err := json.MarshalIndent(d.Status.Config, "", "  ")
+	if err != nil {
+		http.Error(w, "failed to encode config", http.StatusInternalServerError)
+		return
+	}
+	d.Config = string(cfg)
+
+	buf := &bytes.Buffer{}
+	err = dashboardTemplate.Execute(buf, d)
+	if err != nil {
+		http.Error(w, "failed to render dashboard", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -3,8 +3,16 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
+// heartbeat holds the time of the last heartbeat of a component and the
+// timeout after which the component is considered stale
+type heartbeat struct {
+	last    time.Time
+	timeout time.Duration
+}
+
 // GetHealth get health of the server
 func (s *Server) GetHealth() bool {
 	s.m.Lock()
@@ -14,9 +22,26 @@ func (s *Server) GetHealth() bool {
 			return false
 		}
 	}
+	for _, h := range s.heartbeats {
+		if h.timeout > 0 && time.Since(h.last) > h.timeout {
+			return false
+		}
+	}
 	return true
 }
 
+// Heartbeat signals that a component is alive. The component is considered
+// unhealthy, if there is no further heartbeat within the timeout. A timeout of
+// zero disables the check.
+func (s *Server) Heartbeat(key string, timeout time.Duration) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.heartbeats[key] = heartbeat{
+		last:    time.Now(),
+		timeout: timeout,
+	}
+}
+
 // SetHealth set health of a component
 func (s *Server) SetHealth(key string, health bool) {
 	s.m.Lock()
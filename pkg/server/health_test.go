@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServer_Heartbeat(t *testing.T) {
+	const reconcilationInterval = time.Minute
+	tests := []struct {
+		name              string
+		livenessIntervals int
+		elapsed           time.Duration
+		want              bool
+	}{
+		{"fresh", 5, 0, true},
+		{"within intervals", 5, 4 * reconcilationInterval, true},
+		{"past intervals", 5, 5*reconcilationInterval + time.Second, false},
+		{"disabled", -1, 24 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{m: &sync.Mutex{}, health: map[string]bool{}, heartbeats: map[string]heartbeat{}}
+			s.Heartbeat("controller", time.Duration(tt.livenessIntervals)*reconcilationInterval)
+			// Advance the time by moving the last heartbeat into the past
+			h := s.heartbeats["controller"]
+			h.last = h.last.Add(-tt.elapsed)
+			s.heartbeats["controller"] = h
+
+			if got := s.GetHealth(); got != tt.want {
+				t.Errorf("GetHealth() = %v, want %v", got, tt.want)
+			}
+			w := httptest.NewRecorder()
+			s.HealthHandler(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if got := w.Code == http.StatusOK; got != tt.want {
+				t.Errorf("HealthHandler() status = %v, want healthy %v", w.Code, tt.want)
+			}
+
+			// A new heartbeat makes the controller healthy again
+			s.Heartbeat("controller", time.Duration(tt.livenessIntervals)*reconcilationInterval)
+			if !s.GetHealth() {
+				t.Errorf("GetHealth() = false after heartbeat")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/shaardie/k8s-restarter/pkg/audit"
+)
+
+// SetPaused sets the pause state as seen by the controller
+func (s *Server) SetPaused(paused bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.paused = paused
+}
+
+// GetPaused returns the pause state as seen by the controller
+func (s *Server) GetPaused() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.paused
+}
+
+// PauseHandler returns a handler to get the pause state or to pause or resume
+// the automatic restarts
+func (s *Server) PauseHandler(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			writeJSON(w, map[string]bool{"paused": s.GetPaused()})
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, status, err := s.authorize(r, "create", "pause", "", "")
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		actions := s.getActions()
+		if actions == nil {
+			http.Error(w, "not leading", http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(audit.WithUser(r.Context(), user), actionTimeout)
+		defer cancel()
+		writeActionResult(w, r, actions.SetPaused(ctx, paused))
+	}
+}
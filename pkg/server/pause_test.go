@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testActions records the pause state set via the API
+type testActions struct {
+	paused *bool
+}
+
+func (a *testActions) Restart(ctx context.Context, namespace, kind, name string) error {
+	return nil
+}
+
+func (a *testActions) Postpone(ctx context.Context, namespace, kind, name string, until time.Time) error {
+	return nil
+}
+
+func (a *testActions) SetPaused(ctx context.Context, paused bool) error {
+	a.paused = &paused
+	return nil
+}
+
+func (a *testActions) RestartImage(ctx context.Context, image string) ([]ImageRestart, error) {
+	return nil, nil
+}
+
+// testClientset returns a clientset authenticating every token and allowing
+// the access, if allowed is set
+func testClientset(allowed bool) *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authenticationv1.TokenReview{Status: authenticationv1.TokenReviewStatus{
+			Authenticated: true,
+			User:          authenticationv1.UserInfo{Username: "operator"},
+		}}, nil
+	})
+	clientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+	return clientset
+}
+
+func TestServer_PauseHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		allowed    bool
+		leading    bool
+		wantStatus int
+	}{
+		{"get", http.MethodGet, "", false, true, http.StatusOK},
+		{"pause", http.MethodPost, "token", true, true, http.StatusOK},
+		{"missing token", http.MethodPost, "", true, true, http.StatusUnauthorized},
+		{"forbidden", http.MethodPost, "token", false, true, http.StatusForbidden},
+		{"not leading", http.MethodPost, "token", true, false, http.StatusServiceUnavailable},
+		{"wrong method", http.MethodDelete, "token", true, true, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{m: &sync.Mutex{}, logger: zap.NewNop(), clientset: testClientset(tt.allowed)}
+			actions := &testActions{}
+			if tt.leading {
+				s.SetActions(actions)
+			}
+			r := httptest.NewRequest(tt.method, "/api/v1/pause", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			s.PauseHandler(true)(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("PauseHandler() status = %v, want %v, body %v", w.Code, tt.wantStatus, w.Body.String())
+			}
+			wantCalled := tt.method == http.MethodPost && tt.wantStatus == http.StatusOK
+			if called := actions.paused != nil; called != wantCalled {
+				t.Fatalf("PauseHandler() called SetPaused = %v, want %v", called, wantCalled)
+			}
+			if wantCalled && !*actions.paused {
+				t.Errorf("PauseHandler() resumed instead of paused")
+			}
+		})
+	}
+}
@@ -6,25 +6,39 @@ import (
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shaardie/k8s-restarter/pkg/config"
 	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
 )
 
 type Server struct {
 	http.Server
-	logger *zap.Logger
-	health map[string]bool
-	m      *sync.Mutex
+	logger     *zap.Logger
+	clientset  kubernetes.Interface
+	health     map[string]bool
+	heartbeats map[string]heartbeat
+	apps       []App
+	actions    Actions
+	paused     bool
+	leader     string
+	cfg        *config.Config
+	cfgError   string
+	restarts   []Restart
+	m          *sync.Mutex
 }
 
-// New returns a new server
-func New(logger *zap.Logger, addr string) *Server {
+// New returns a new server. The clientset is used to authenticate and
+// authorize requests to the mutating API.
+func New(logger *zap.Logger, addr string, clientset kubernetes.Interface) *Server {
 	s := &Server{
 		Server: http.Server{
 			Addr: addr,
 		},
-		logger: logger,
-		health: map[string]bool{},
-		m:      &sync.Mutex{},
+		logger:     logger,
+		clientset:  clientset,
+		health:     map[string]bool{},
+		heartbeats: map[string]heartbeat{},
+		m:          &sync.Mutex{},
 	}
 	// routing
 	http.HandleFunc("/healthz", s.LoggerHandlerFunc(s.HealthHandler))
@@ -32,6 +46,14 @@ func New(logger *zap.Logger, addr string) *Server {
 		fmt.Fprintf(w, "ok")
 	}))
 	http.Handle("/metrics", s.LoggerHandlerFunc(promhttp.Handler().ServeHTTP))
+	http.HandleFunc("/api/v1/apps", s.LoggerHandlerFunc(s.AppsHandler))
+	http.HandleFunc("/api/v1/apps/", s.LoggerHandlerFunc(s.AppActionHandler))
+	http.HandleFunc("/api/v1/images/restart", s.LoggerHandlerFunc(s.ImageRestartHandler))
+	http.HandleFunc("/api/v1/pause", s.LoggerHandlerFunc(s.PauseHandler(true)))
+	http.HandleFunc("/api/v1/resume", s.LoggerHandlerFunc(s.PauseHandler(false)))
+	http.HandleFunc("/api/v1/status", s.LoggerHandlerFunc(s.StatusHandler))
+	http.HandleFunc("/api/v1/restarts", s.LoggerHandlerFunc(s.RestartsHandler))
+	http.HandleFunc("/", s.LoggerHandlerFunc(s.DashboardHandler))
 
 	return s
 }
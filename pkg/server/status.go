@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/shaardie/k8s-restarter/pkg/config"
+)
+
+// maxRestarts is the number of recent restarts kept
+const maxRestarts = 100
+
+// Restart represents a single restart of an app
+type Restart struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Trigger   string    `json:"trigger"`
+}
+
+// Status represents the status of the controller
+type Status struct {
+	Leader      string         `json:"leader"`
+	Paused      bool           `json:"paused"`
+	Config      *config.Config `json:"config"`
+	ConfigError string         `json:"configError,omitempty"`
+}
+
+// SetLeader sets the identity of the current leader
+func (s *Server) SetLeader(identity string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.leader = identity
+}
+
+// SetConfig sets the configuration used by the controller
+func (s *Server) SetConfig(cfg *config.Config) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.cfg = cfg
+}
+
+// SetConfigError sets the error of the last rejected configuration. A nil
+// error resets it.
+func (s *Server) SetConfigError(err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.cfgError = ""
+	if err != nil {
+		s.cfgError = err.Error()
+	}
+}
+
+// GetStatus returns the status of the controller. It is served without
+// authentication, so the configuration is redacted.
+func (s *Server) GetStatus() Status {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return Status{
+		Leader:      s.leader,
+		Paused:      s.paused,
+		Config:      s.cfg.Redacted(),
+		ConfigError: s.cfgError,
+	}
+}
+
+// AddRestart adds a restart to the recent restarts
+func (s *Server) AddRestart(r Restart) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.restarts = append(s.restarts, r)
+	if len(s.restarts) > maxRestarts {
+		s.restarts = s.restarts[len(s.restarts)-maxRestarts:]
+	}
+}
+
+// GetRestarts returns the recent restarts, latest first
+func (s *Server) GetRestarts() []Restart {
+	s.m.Lock()
+	defer s.m.Unlock()
+	restarts := make([]Restart, len(s.restarts))
+	for i, r := range s.restarts {
+		restarts[len(s.restarts)-1-i] = r
+	}
+	return restarts
+}
+
+// StatusHandler handles requests for the status of the controller
+func (s *Server) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.GetStatus())
+}
+
+// RestartsHandler handles requests listing the recent restarts
+func (s *Server) RestartsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.GetRestarts())
+}